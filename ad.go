@@ -0,0 +1,234 @@
+package mep
+
+import (
+	"math"
+)
+
+// Differentiable - optionally implemented by operators to give their partial derivatives to the
+// automatic differentiation (see Model.Gradient); other operators are differentiated numerically
+type Differentiable interface {
+	// Partial - derivative with respect to argument k, at the argument values x of a row
+	Partial(k int, x []float64) float64
+}
+
+// builtinPartials - partial derivatives of the builtin operators; piecewise constant operators
+// have zero derivatives, and conditionals those of the chosen argument
+var builtinPartials = map[string]func(k int, x []float64) float64{
+	"add":   func(k int, x []float64) float64 { return 1 },
+	"sub":   func(k int, x []float64) float64 { return []float64{1, -1}[k] },
+	"mul":   func(k int, x []float64) float64 { return x[1-k] },
+	"div":   divPartial,
+	"sin":   func(k int, x []float64) float64 { return math.Cos(x[0]) },
+	"cos":   func(k int, x []float64) float64 { return -math.Sin(x[0]) },
+	"tan":   func(k int, x []float64) float64 { return 1 / (math.Cos(x[0]) * math.Cos(x[0])) },
+	"exp":   func(k int, x []float64) float64 { return math.Exp(x[0]) },
+	"log":   logPartial,
+	"sqrt":  func(k int, x []float64) float64 { return 0.5 / math.Sqrt(x[0]) },
+	"abs":   func(k int, x []float64) float64 { return sign(x[0]) },
+	"max":   func(k int, x []float64) float64 { return chosen(k, x[0] > x[1], 0, 1) },
+	"min":   func(k int, x []float64) float64 { return chosen(k, x[0] < x[1], 0, 1) },
+	"ifgtz": func(k int, x []float64) float64 { return chosen(k, x[0] > 0, 1, 2) },
+	"ifltz": func(k int, x []float64) float64 { return chosen(k, x[0] < 0, 1, 2) },
+	"ifgt":  func(k int, x []float64) float64 { return chosen(k, x[0] > x[1], 2, 3) },
+	"iflt":  func(k int, x []float64) float64 { return chosen(k, x[0] < x[1], 2, 3) },
+	"pow": func(k int, x []float64) float64 {
+		if k == 0 {
+			return x[1] * math.Pow(x[0], x[1]-1)
+		}
+		if x[0] <= 0 {
+			return 0
+		}
+		return math.Pow(x[0], x[1]) * math.Log(x[0])
+	},
+	"log10":   log10Partial,
+	"log2":    log2Partial,
+	"inv":     invPartial,
+	"square":  func(k int, x []float64) float64 { return 2 * x[0] },
+	"tanh":    func(k int, x []float64) float64 { return 1 - math.Tanh(x[0])*math.Tanh(x[0]) },
+	"sigmoid": func(k int, x []float64) float64 { s := 1 / (1 + math.Exp(-x[0])); return s * (1 - s) },
+	"atan":    func(k int, x []float64) float64 { return 1 / (1 + x[0]*x[0]) },
+	"atan2": func(k int, x []float64) float64 {
+		return []float64{x[1], -x[0]}[k] / (x[0]*x[0] + x[1]*x[1])
+	},
+	"fmod":   fmodPartial,
+	"hypot":  func(k int, x []float64) float64 { return x[k] / math.Hypot(x[0], x[1]) },
+	"neg":    func(k int, x []float64) float64 { return -1 },
+	"cube":   func(k int, x []float64) float64 { return 3 * x[0] * x[0] },
+	"cbrt":   func(k int, x []float64) float64 { return 1 / (3 * math.Cbrt(x[0]*x[0])) },
+	"gauss":  func(k int, x []float64) float64 { return -2 * x[0] * math.Exp(-(x[0] * x[0])) },
+	"pdiv":   protectedPartial(1, divPartial),
+	"pinv":   protectedPartial(0, invPartial),
+	"plog":   protectedPartial(0, logPartial),
+	"plog10": protectedPartial(0, log10Partial),
+	"plog2":  protectedPartial(0, log2Partial),
+	"psqrt": protectedPartial(0, func(k int, x []float64) float64 {
+		return sign(x[0]) * 0.5 / math.Sqrt(math.Abs(x[0]))
+	}),
+	"ppow": func(k int, x []float64) float64 {
+		a := math.Abs(x[0])
+		if a < 1e-6 {
+			return 0
+		}
+		if k == 0 {
+			return sign(x[0]) * x[1] * math.Pow(a, x[1]-1)
+		}
+		return math.Pow(a, x[1]) * math.Log(a)
+	},
+	"pfmod": protectedPartial(1, fmodPartial),
+	// ifbgt, ifblt, and, or, pow10, floor, ceil, sign and step are piecewise constant
+	"ifbgt": zeroPartial, "ifblt": zeroPartial, "and": zeroPartial, "or": zeroPartial, "pow10": zeroPartial,
+	"floor": zeroPartial, "ceil": zeroPartial, "sign": zeroPartial, "step": zeroPartial,
+}
+
+func zeroPartial(k int, x []float64) float64 {
+	return 0
+}
+
+func divPartial(k int, x []float64) float64 {
+	if k == 0 {
+		return 1 / x[1]
+	}
+	return -x[0] / (x[1] * x[1])
+}
+
+func invPartial(k int, x []float64) float64 {
+	return -1 / (x[0] * x[0])
+}
+
+// logPartial - also the derivative of log|x|
+func logPartial(k int, x []float64) float64 {
+	return 1 / x[0]
+}
+
+func log10Partial(k int, x []float64) float64 {
+	return 1 / (x[0] * math.Ln10)
+}
+
+func log2Partial(k int, x []float64) float64 {
+	return 1 / (x[0] * math.Ln2)
+}
+
+func fmodPartial(k int, x []float64) float64 {
+	if k == 0 {
+		return 1
+	}
+	return -math.Trunc(x[0] / x[1])
+}
+
+// chosen - partial derivative of a selection of argument a if cond, else b
+func chosen(k int, cond bool, a, b int) float64 {
+	if cond && k == a || !cond && k == b {
+		return 1
+	}
+	return 0
+}
+
+// protectedPartial - derivative of the protected version of an operator, constant where argument arg is near 0
+func protectedPartial(arg int, f func(k int, x []float64) float64) func(k int, x []float64) float64 {
+	return func(k int, x []float64) float64 {
+		if math.Abs(x[arg]) < 1e-6 {
+			return 0
+		}
+		return f(k, x)
+	}
+}
+
+// partial - derivative of the operator with respect to argument k at x, numerical when it is not known
+func partial(o Operator, k int, x []float64) float64 {
+	if d, ok := o.(Differentiable); ok {
+		return d.Partial(k, x)
+	}
+	if b, ok := o.(*builtin); ok && builtinPartials[b.name] != nil {
+		return builtinPartials[b.name](k, x)
+	}
+	// central difference
+	h := 1e-6 * math.Max(1, math.Abs(x[k]))
+	args := make([][]float64, len(x))
+	for i := range x {
+		args[i] = []float64{x[i]}
+	}
+	out := []float64{0}
+	args[k][0] = x[k] + h
+	o.Eval(args, out)
+	f1 := out[0]
+	args[k][0] = x[k] - h
+	o.Eval(args, out)
+	return (f1 - out[0]) / (2 * h)
+}
+
+// dual - the output of the model on each row of data, and its derivatives with respect to each of
+// the terminals wrt (variable indexes, or the number of variables plus a constant index), computed
+// in forward mode: along with its values, each gene carries its derivatives, as dual numbers do
+func (md *Model) dual(data [][]float64, wrt []int) ([]float64, [][]float64) {
+
+	numVariables := len(md.Labels)
+	active := activeGenes(md.program, md.output)
+	values := make([][]float64, len(md.program))
+	derivs := make([][][]float64, len(md.program)) // gene, terminal, row
+	for i := 0; i <= md.output; i++ {
+		if !active[i] {
+			continue
+		}
+		gene := md.program[i]
+		values[i] = make([]float64, len(data))
+		derivs[i] = make([][]float64, len(wrt))
+		for d := range wrt {
+			derivs[i][d] = make([]float64, len(data))
+		}
+		if gene.op >= 0 {
+			for k := range data {
+				if gene.op < numVariables {
+					values[i][k] = data[k][gene.op]
+				} else {
+					values[i][k] = md.constants[gene.op-numVariables]
+				}
+			}
+			for d, op := range wrt {
+				if op == gene.op {
+					for k := range data {
+						derivs[i][d][k] = 1
+					}
+				}
+			}
+			continue
+		}
+
+		o := operatorOf(gene.op).Operator
+		execute(gene, values, values[i])
+		args := gene.args()
+		x := make([]float64, len(args))
+		for k := range data {
+			for a, adr := range args {
+				x[a] = values[adr][k]
+			}
+			for a, adr := range args {
+				p := partial(o, a, x)
+				for d := range wrt {
+					// arguments not depending on the terminal do not contribute, even with an infinite partial
+					if t := derivs[adr][d][k]; t != 0 {
+						derivs[i][d][k] += p * t
+					}
+				}
+			}
+		}
+	}
+	return values[md.output], derivs[md.output]
+}
+
+// Gradient - derivatives of the output with respect to each input variable, on each row of data
+// (gradient[row][variable]), for sensitivity analysis
+func (md *Model) Gradient(data [][]float64) [][]float64 {
+	wrt := make([]int, len(md.Labels))
+	for i := range wrt {
+		wrt[i] = i
+	}
+	_, derivs := md.dual(data, wrt)
+	gradient := make([][]float64, len(data))
+	for k := range gradient {
+		gradient[k] = make([]float64, len(wrt))
+		for d := range wrt {
+			gradient[k][d] = derivs[d][k]
+		}
+	}
+	return gradient
+}
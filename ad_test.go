@@ -0,0 +1,94 @@
+package mep
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGradient(t *testing.T) {
+
+	md, err := ParseModel("x0*x0+sin(x1)*x0", []string{"x0", "x1"})
+	ok(t, err)
+	data := [][]float64{{1, 2}, {-3, 0.5}}
+	for k, g := range md.Gradient(data) {
+		x0, x1 := data[k][0], data[k][1]
+		equals(t, 2*x0+math.Sin(x1), g[0])
+		equals(t, math.Cos(x1)*x0, g[1])
+	}
+
+	// the protected operators have finite derivatives at 0
+	md, err = ParseModel("psqrt(x0)+pdiv(x1,x0)+plog(x0)", []string{"x0", "x1"})
+	ok(t, err)
+	equals(t, [][]float64{{0, 0}}, md.Gradient([][]float64{{0, 1}}))
+}
+
+func TestDualConstants(t *testing.T) {
+
+	// the derivatives of every operator, and the numerical ones of operators without
+	// known derivatives, agree with finite differences of the output
+	labels := []string{"x0", "x1"}
+	data := [][]float64{{0.3, 1.7}, {2.1, -0.6}, {-1.3, 0.9}}
+	for _, o := range registeredOperators {
+		name := o.Name()
+		args := []string{"x0", "0.4*x1+0.7"}
+		if o.Arity() > 2 {
+			args = append(args, "0.9*x0", "x1-0.2")
+		}
+		expr := name + "(" + args[0]
+		for _, a := range args[1:o.Arity()] {
+			expr += "," + a
+		}
+		expr += ")"
+		md, err := ParseModel(expr, labels)
+		ok(t, err)
+
+		wrt := make([]int, len(md.constants))
+		for i := range wrt {
+			wrt[i] = len(labels) + i
+		}
+		values, derivs := md.dual(data, wrt)
+		predicted := md.Predict(data)
+		for d := range wrt {
+			h := 1e-6
+			c := md.constants[d]
+			md.constants[d] = c + h
+			up := md.Predict(data)
+			md.constants[d] = c - h
+			down := md.Predict(data)
+			md.constants[d] = c
+			for k := range data {
+				if math.IsNaN(values[k]) {
+					continue // out of the domain
+				}
+				equals(t, predicted[k], values[k])
+				numerical := (up[k] - down[k]) / (2 * h)
+				if math.Abs(numerical-derivs[d][k]) > 1e-4*math.Max(1, math.Abs(numerical)) {
+					t.Errorf("%s: row %d: d/dc%d = %v, numerically %v", expr, k, d, derivs[d][k], numerical)
+				}
+			}
+		}
+	}
+}
+
+func TestGradientConstOptimizers(t *testing.T) {
+
+	// Adam converges slowly on such an ill-conditioned problem; the tolerances are relative
+	// to the initial fitness, that depends on the random training data
+	for optimizer, tolerance := range map[ConstOptimizer]float64{Adam: 1e-2, LevenbergMarquardt: 1e-6} {
+		// e*x0*x0 + pi*x0
+		m := New(NewSimpleConstantRegression3(20), TotalErrorFF)
+		m.SetConst(nil, 2, -1, 1)
+		m.SetPop(10, 1, 10)
+		m.SetFixedOutput(true)
+		m.SetConstOptimizer(optimizer)
+		m.SetConstOptimization(1, 1, 3000)
+		md, err := m.ParseExpr("2*x0*x0+3*x0")
+		ok(t, err)
+		c := m.seedChromosome(0, md)
+		before := c.fitness
+		m.optimizeChromosome(0, &c)
+		if c.fitness > tolerance*before {
+			t.Errorf("%d: fitness %f after optimization of %f", optimizer, c.fitness, before)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package mep
+
+import (
+	"testing"
+)
+
+// crossoverParents - parents whose genes and constants tell them apart
+func crossoverParents(m *Mep) (parent1, parent2, offspring1, offspring2 chromosome) {
+	parent1, parent2 = m.randomChromosome(0), m.randomChromosome(0)
+	offspring1, offspring2 = m.randomChromosome(0), m.randomChromosome(0)
+	for i := range parent1.program {
+		parent1.program[i] = instruction{op: 0}
+		parent2.program[i] = instruction{op: 1}
+	}
+	for i := range parent1.constants {
+		parent1.constants[i] = 1
+		parent2.constants[i] = 2
+	}
+	return
+}
+
+func TestCrossover(t *testing.T) {
+
+	m := New(NewPythagorean(10), TotalErrorFF)
+	m.SetConst(nil, 10, -1, 1)
+	m.SetPop(10, 1, 20)
+
+	crossovers := map[string]func(p1, p2, o1, o2 *chromosome){
+		"onecutpoint": m.oneCutPointCrossover,
+		"uniform":     m.uniformCrossover,
+		"twopoint":    func(p1, p2, o1, o2 *chromosome) { m.nPointCrossover(2, p1, p2, o1, o2) },
+		"npoint":      func(p1, p2, o1, o2 *chromosome) { m.nPointCrossover(5, p1, p2, o1, o2) },
+	}
+	for name, crossover := range crossovers {
+		mixedGenes, mixedConstants := 0, 0
+		for trial := 0; trial < 20; trial++ {
+			p1, p2, o1, o2 := crossoverParents(m)
+			crossover(&p1, &p2, &o1, &o2)
+			genes, constants := map[int]int{}, map[float64]int{}
+			for i := range o1.program {
+				genes[o1.program[i].op]++
+				// the offspring are complementary
+				equals(t, 1, o1.program[i].op+o2.program[i].op)
+			}
+			for i := range o1.constants {
+				constants[o1.constants[i]]++
+				equals(t, 3.0, o1.constants[i]+o2.constants[i])
+			}
+			if len(genes) == 2 {
+				mixedGenes++
+			}
+			if len(constants) == 2 {
+				mixedConstants++
+			}
+		}
+		if mixedGenes < 10 || mixedConstants < 10 {
+			t.Errorf("%s: offspring mixing both parents: %d genes, %d constants of 20", name, mixedGenes, mixedConstants)
+		}
+	}
+}
+
+func TestSubtreeCrossover(t *testing.T) {
+
+	m := New(NewPythagorean(10), TotalErrorFF)
+	m.SetConst(nil, 5, -1, 1)
+	m.SetPop(10, 1, 20)
+
+	for trial := 0; trial < 20; trial++ {
+		p1, p2 := m.pop[0][0], m.pop[0][1+trial%9]
+		o1, o2 := m.randomChromosome(0), m.randomChromosome(0)
+		m.subtreeCrossover(&p1, &p2, &o1, &o2)
+
+		for _, c := range []struct{ receiver, donor, offspring *chromosome }{{&p1, &p2, &o1}, {&p2, &p1, &o2}} {
+			// each gene is either the receiver's, or the donor's along with its sub-expression
+			for i := range c.offspring.program {
+				if c.offspring.program[i] == c.receiver.program[i] {
+					continue
+				}
+				equals(t, c.donor.program[i], c.offspring.program[i])
+				donor, offspring := m.model(*c.donor), m.model(*c.offspring)
+				donor.output, offspring.output = i, i
+				equals(t, donor.String(), offspring.String())
+			}
+			m.eval(m.results[0], c.offspring)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package mep
+
+import (
+	"fmt"
+	"math"
+)
+
+// Derivative - model computing the symbolic derivative of the model with respect to the named
+// input variable, simplified. The protected operators are differentiated through their definition,
+// and the piecewise constant ones have zero derivatives; registered operators cannot be differentiated.
+func (md *Model) Derivative(variable string) (*Model, error) {
+	wrt, err := md.variable(variable)
+	if err != nil {
+		return nil, err
+	}
+	d, err := md.tree().expand().derive(wrt)
+	if err != nil {
+		return nil, err
+	}
+	return newModel(d.simplify(), md.Labels), nil
+}
+
+// variable - index of the input variable with the given label
+func (md *Model) variable(label string) (int, error) {
+	for i, l := range md.Labels {
+		if l == label {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("unknown variable %s", label)
+}
+
+// BestDerivative - symbolic derivative of the best individual with respect to the named input variable,
+// and its largest difference from finite differences on the training data (see DerivativeError)
+func (m *Mep) BestDerivative(variable string) (*Model, float64, error) {
+	md := m.BestModel()
+	derivative, err := md.Derivative(variable)
+	if err != nil {
+		return nil, 0, err
+	}
+	maxError, err := md.DerivativeError(derivative, variable, m.td.Train)
+	return derivative, maxError, err
+}
+
+// DerivativeError - largest difference between the derivative (see Derivative) and central finite
+// differences of the model with respect to the named variable on the rows of data, relative to the
+// magnitude of the finite differences when it is above 1. Rows where either is not finite are skipped.
+func (md *Model) DerivativeError(derivative *Model, variable string, data [][]float64) (float64, error) {
+	wrt, err := md.variable(variable)
+	if err != nil {
+		return 0, err
+	}
+	above := make([][]float64, len(data))
+	below := make([][]float64, len(data))
+	steps := make([]float64, len(data))
+	for k, row := range data {
+		steps[k] = 1e-6 * math.Max(1, math.Abs(row[wrt]))
+		above[k] = append([]float64(nil), row...)
+		above[k][wrt] += steps[k]
+		below[k] = append([]float64(nil), row...)
+		below[k][wrt] -= steps[k]
+	}
+	f1, f2 := md.Predict(above), md.Predict(below)
+	symbolic := derivative.Predict(data)
+	maxError := 0.0
+	for k := range data {
+		numeric := (f1[k] - f2[k]) / (2 * steps[k])
+		if !finite([]float64{numeric, symbolic[k]}) {
+			continue
+		}
+		maxError = math.Max(maxError, math.Abs(symbolic[k]-numeric)/math.Max(1, math.Abs(numeric)))
+	}
+	return maxError, nil
+}
+
+// derive - tree of the derivative with respect to variable wrt, by the chain rule
+func (n *node) derive(wrt int) (*node, error) {
+	switch n.kind {
+	case variableNode:
+		if n.op == wrt {
+			return constantNodeOf(1), nil
+		}
+		return constantNodeOf(0), nil
+	case constantNode:
+		return constantNodeOf(0), nil
+	}
+
+	d := make([]*node, len(n.args))
+	for i, a := range n.args {
+		var err error
+		if d[i], err = a.derive(wrt); err != nil {
+			return nil, err
+		}
+	}
+	a := n.args[0]
+	var b *node
+	if len(n.args) > 1 {
+		b = n.args[1]
+	}
+	op := func(code int, args ...*node) *node { return operatorNodeOf(code, args...) }
+	c := constantNodeOf
+
+	switch n.op {
+	case -1: // add
+		return sum(d[0], d[1]), nil
+	case -2: // sub
+		return difference(d[0], d[1]), nil
+	case -3: // mul
+		return sum(times(d[0], b), times(a, d[1])), nil
+	case -4: // div
+		return over(difference(times(d[0], b), times(a, d[1])), op(-29, b)), nil
+	case -5: // sin
+		return times(op(-6, a), d[0]), nil
+	case -6: // cos
+		return times(c(-1), times(op(-5, a), d[0])), nil
+	case -7: // tan
+		return over(d[0], op(-29, op(-6, a))), nil
+	case -8: // exp
+		return times(n, d[0]), nil
+	case -9: // log
+		return over(d[0], a), nil
+	case -10: // sqrt
+		return over(d[0], times(c(2), n)), nil
+	case -11: // abs
+		return times(op(-40, a), d[0]), nil
+	case -12: // max
+		return op(-16, a, b, d[0], d[1]), nil
+	case -13: // min
+		return op(-17, a, b, d[0], d[1]), nil
+	case -14, -15: // ifgtz, ifltz
+		return op(n.op, a, d[1], d[2]), nil
+	case -16, -17: // ifgt, iflt
+		return op(n.op, a, b, d[2], d[3]), nil
+	case -18, -19, -20, -21, -23, -26, -27, -40, -41: // ifbgt, ifblt, and, or, pow10, floor, ceil, sign, step
+		return c(0), nil
+	case -22: // pow
+		if d[1].isConst(0) {
+			// constant exponent, also defined for negative bases
+			return times(times(b, op(-22, a, difference(b, c(1)))), d[0]), nil
+		}
+		return times(n, sum(times(d[1], op(-9, a)), over(times(b, d[0]), a))), nil
+	case -24: // log10
+		return over(d[0], times(a, c(math.Ln10))), nil
+	case -25: // log2
+		return over(d[0], times(a, c(math.Ln2))), nil
+	case -28: // inv
+		return times(c(-1), over(d[0], op(-29, a))), nil
+	case -29: // square
+		return times(times(c(2), a), d[0]), nil
+	case -30: // tanh
+		return times(difference(c(1), op(-29, n)), d[0]), nil
+	case -31: // sigmoid
+		return times(times(n, difference(c(1), n)), d[0]), nil
+	case -32: // atan
+		return over(d[0], sum(c(1), op(-29, a))), nil
+	case -33: // atan2
+		return over(difference(times(b, d[0]), times(a, d[1])), sum(op(-29, a), op(-29, b))), nil
+	case -34: // fmod, a - trunc(a/b)*b
+		q := op(-4, a, b)
+		return difference(d[0], times(op(-14, q, op(-26, q), op(-27, q)), d[1])), nil
+	case -35: // hypot
+		return over(sum(times(a, d[0]), times(b, d[1])), n), nil
+	case -36: // neg
+		return times(c(-1), d[0]), nil
+	case -37: // cube
+		return times(times(c(3), op(-29, a)), d[0]), nil
+	case -38: // cbrt
+		return over(d[0], times(c(3), op(-29, n))), nil
+	case -39: // gauss
+		return times(times(c(-2), times(a, n)), d[0]), nil
+	}
+	return nil, fmt.Errorf("no symbolic derivative of %s", operatorOf(n.op).Name())
+}
+
+// sum, difference, times and over - arithmetic nodes, dropping zero derivatives and unit factors
+func sum(a, b *node) *node {
+	switch {
+	case a.isConst(0):
+		return b
+	case b.isConst(0):
+		return a
+	}
+	return operatorNodeOf(-1, a, b)
+}
+
+func difference(a, b *node) *node {
+	switch {
+	case b.isConst(0):
+		return a
+	case a.isConst(0):
+		return times(constantNodeOf(-1), b)
+	}
+	return operatorNodeOf(-2, a, b)
+}
+
+func times(a, b *node) *node {
+	switch {
+	case a.isConst(0) || b.isConst(0):
+		return constantNodeOf(0)
+	case a.isConst(1):
+		return b
+	case b.isConst(1):
+		return a
+	}
+	return operatorNodeOf(-3, a, b)
+}
+
+func over(a, b *node) *node {
+	switch {
+	case a.isConst(0):
+		return constantNodeOf(0)
+	case b.isConst(1):
+		return a
+	}
+	return operatorNodeOf(-4, a, b)
+}
@@ -0,0 +1,72 @@
+package mep
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDerivative(t *testing.T) {
+
+	md, err := ParseModel("x0*x0+sin(x1)*x0", []string{"x0", "x1"})
+	ok(t, err)
+	d0, err := md.Derivative("x0")
+	ok(t, err)
+	d1, err := md.Derivative("x1")
+	ok(t, err)
+	equals(t, "x0*cos(x1)", d1.String())
+	data := [][]float64{{1, 2}, {-3, 0.5}}
+	p0, p1 := d0.Predict(data), d1.Predict(data)
+	for k, row := range data {
+		equals(t, 2*row[0]+math.Sin(row[1]), p0[k])
+		equals(t, math.Cos(row[1])*row[0], p1[k])
+	}
+
+	// a constant derivative
+	md, err = ParseModel("3*x0+x1", []string{"x0", "x1"})
+	ok(t, err)
+	d0, err = md.Derivative("x0")
+	ok(t, err)
+	equals(t, "3", d0.String())
+
+	_, err = md.Derivative("x2")
+	if err == nil {
+		t.Errorf("derivative with respect to an unknown variable")
+	}
+}
+
+func TestDerivativeOperators(t *testing.T) {
+
+	// the symbolic derivatives of every builtin operator agree with finite differences,
+	// and registered operators cannot be differentiated
+	labels := []string{"x0", "x1"}
+	data := [][]float64{{0.3, 1.7}, {2.1, -0.6}, {-1.3, 0.9}}
+	for i, o := range registeredOperators {
+		args := []string{"x0*x1", "0.4*x1+0.7"}
+		if o.Arity() > 2 {
+			args = append(args, "0.9*x0", "x1-0.2")
+		}
+		expr := o.Name() + "(" + args[0]
+		for _, a := range args[1:o.Arity()] {
+			expr += "," + a
+		}
+		expr += ")"
+		md, err := ParseModel(expr, labels)
+		ok(t, err)
+
+		for _, variable := range labels {
+			derivative, err := md.Derivative(variable)
+			if i >= len(builtinOperators) {
+				if err == nil {
+					t.Errorf("%s: derivative of a registered operator", expr)
+				}
+				continue
+			}
+			ok(t, err)
+			maxError, err := md.DerivativeError(derivative, variable, data)
+			ok(t, err)
+			if maxError > 1e-4 {
+				t.Errorf("%s: d/d%s = %s, error %v", expr, variable, derivative, maxError)
+			}
+		}
+	}
+}
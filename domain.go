@@ -0,0 +1,92 @@
+package mep
+
+import (
+	"math"
+)
+
+// DomainPolicy - how evaluation deals with operators applied outside of their domain,
+// such as a division by (almost) zero, the log of a negative number or an overflowing exp
+type DomainPolicy int
+
+const (
+	// Repair - mutate a gene whose operator fails its domain check (see DomainChecker), such as
+	// a division by almost zero, into a variable; other NaN or infinite results are kept (default)
+	Repair DomainPolicy = iota
+	// RepairAll - also mutate into a variable the genes turning finite arguments into NaN or infinity
+	RepairAll
+	// Protect - use the protected versions of div, inv, log, log10, log2, sqrt, pow and fmod
+	// (pdiv, pinv, ...), which are defined everywhere, and repair the remaining errors as RepairAll
+	// does, for instance an overflowing exp. The protected versions are internal: they appear in
+	// the expressions, but are not listed by Oper and cannot be enabled by SetOper.
+	Protect
+	// Penalize - give the gene, and the genes using it, an infinite fitness, so that they are never
+	// the output; with a fixed output the whole individual gets an infinite fitness
+	Penalize
+)
+
+// SetDomainPolicy - how domain errors are handled. A domain error is an operator whose
+// DomainChecker fails or, except with Repair, that turns finite arguments into NaN or infinity.
+// The population is reinitialised.
+func (m *Mep) SetDomainPolicy(policy DomainPolicy) {
+	if policy < Repair || policy > Penalize {
+		panic("invalid domain policy")
+	}
+	m.domainPolicy = policy
+	// initialize population
+	m.randomPopulation()
+}
+
+// apply - evaluate an operator gene into out, reporting a domain error
+func (m *Mep) apply(gene instruction, results [][]float64, out []float64) bool {
+	o := operatorOf(gene.op)
+	args := arguments(gene, results)
+	if dc, ok := o.Operator.(DomainChecker); ok && !dc.InDomain(args) {
+		return false
+	}
+	o.Eval(args, out)
+	return m.domainPolicy == Repair || !domainError(args, out)
+}
+
+// domainError - whether a row of finite arguments gave a NaN or infinite result
+func domainError(args [][]float64, out []float64) bool {
+	for k, v := range out {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			continue
+		}
+		finite := true
+		for _, a := range args {
+			if math.IsNaN(a[k]) || math.IsInf(a[k], 0) {
+				finite = false
+			}
+		}
+		if finite {
+			return true
+		}
+	}
+	return false
+}
+
+// protectedVersion - whether op is the protected version of another operator (see Protect)
+func protectedVersion(op int) bool {
+	for _, protected := range protectedOperators {
+		if op == protected {
+			return true
+		}
+	}
+	return false
+}
+
+// expand - replace the operators defined in terms of others (the protected ones) by their definition
+func (n *node) expand() *node {
+	if n.kind != operatorNode {
+		return n
+	}
+	args := make([]*node, len(n.args))
+	for i, a := range n.args {
+		args[i] = a.expand()
+	}
+	if b, ok := operatorOf(n.op).Operator.(*builtin); ok && b.expand != nil {
+		return b.expand(args)
+	}
+	return operatorNodeOf(n.op, args...)
+}
@@ -0,0 +1,100 @@
+package mep
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDomainPolicy(t *testing.T) {
+
+	td := TrainingData{Labels: []string{"x0", "x1"}}
+	for i := 1; i <= 10; i++ {
+		td.Train = append(td.Train, []float64{float64(i), 0})
+		td.Target = append(td.Target, float64(i))
+	}
+	m := New(td, TotalErrorFF)
+	m.SetPop(10, 1, 10)
+	m.SetFixedOutput(true)
+	md, err := m.ParseExpr("x0/x1")
+	ok(t, err)
+
+	// repair: the division by zero is mutated into a variable
+	c := m.seedChromosome(0, md)
+	if c.program[m.codeLength-1].op < 0 {
+		t.Errorf("division by zero not repaired")
+	}
+
+	// penalize: the division is kept, and never chosen as output
+	m.SetDomainPolicy(Penalize)
+	c = m.seedChromosome(0, md)
+	equals(t, -4, c.program[m.codeLength-1].op)
+	equals(t, math.Inf(1), c.fitness)
+	md, err = m.ParseExpr("x0+x0/x1")
+	ok(t, err)
+	c = m.seedChromosome(0, md)
+	equals(t, math.Inf(1), c.fitness)
+
+	m.SetFixedOutput(false)
+	c = m.seedChromosome(0, md)
+	equals(t, 0.0, c.fitness)
+	equals(t, 0, c.bestIndex)
+
+	// protect: the protected versions are used instead
+	m.SetOper("div", true)
+	m.SetDomainPolicy(Protect)
+	for _, subPop := range m.pop {
+		for _, c := range subPop {
+			for _, gene := range c.program {
+				if gene.op == -4 {
+					t.Fatalf("unprotected division with the protect policy")
+				}
+			}
+		}
+	}
+
+	// the protected versions are internal
+	m.SetOper("pdiv", true)
+	for _, name := range m.Oper(true) {
+		if name == "pdiv" {
+			t.Errorf("protected operator listed")
+		}
+	}
+
+	// an overflowing exp is kept by the default policy, and repaired by the others
+	md, err = m.ParseExpr("exp(x0*x0*x0)")
+	ok(t, err)
+	for _, policy := range []DomainPolicy{Repair, RepairAll, Protect} {
+		m.SetDomainPolicy(policy)
+		c = m.seedChromosome(0, md)
+		kept := false
+		for _, gene := range c.program[:md.Size()] {
+			kept = kept || gene.op == -8
+		}
+		equals(t, policy == Repair, kept)
+	}
+}
+
+func TestProtectedOperators(t *testing.T) {
+
+	labels := []string{"a", "b"}
+	var data [][]float64
+	for _, a := range []float64{-2.5, -1, -1e-7, 0, 1e-7, 0.5, 3} {
+		for _, b := range []float64{-2, -1e-8, 0, 1e-8, 0.5, 2} {
+			data = append(data, []float64{a, b})
+		}
+	}
+	for _, expr := range []string{"pdiv(a,b)", "pinv(a)", "plog(a)", "plog10(a)", "plog2(a)", "psqrt(a)", "ppow(a,b)", "pfmod(a,b)"} {
+		md, err := ParseModel(expr, labels)
+		ok(t, err)
+		// the expansion into plain operators, used by the exports, computes the same
+		expanded, err := ParseModel(md.tree().expand().format("", labels), labels)
+		ok(t, err)
+		want := expanded.Predict(data)
+		for k, v := range md.Predict(data) {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				t.Errorf("%s: row %d: %v", expr, k, v)
+			}
+			equals(t, want[k], v)
+		}
+	}
+}
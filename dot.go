@@ -0,0 +1,62 @@
+package mep
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// BestDot - return the active genes of the best individual as a Graphviz graph
+func (m *Mep) BestDot() string {
+	return m.BestModel().Dot()
+}
+
+// Dot - Graphviz (dot) graph of the genes used to compute the output.
+// Unlike String, sub-expressions shared by several genes are drawn once.
+// Edges point from the arguments to the operators using them (numbered for operators
+// with several arguments) and the output gene is highlighted.
+func (md *Model) Dot() string {
+
+	numVariables := len(md.Labels)
+	active := activeGenes(md.program, md.output)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "digraph mep {\n\trankdir=BT;\n\tnode [fontname=\"Helvetica\"];\n")
+	for i, gene := range md.program {
+		if !active[i] {
+			continue
+		}
+		var label, shape string
+		switch {
+		case gene.op < 0:
+			label, shape = operatorOf(gene.op).Name(), "circle"
+		case gene.op < numVariables:
+			label, shape = md.Labels[gene.op], "box"
+		default:
+			label, shape = strconv.FormatFloat(md.constants[gene.op-numVariables], 'g', -1, 64), "plaintext"
+		}
+		attrs := fmt.Sprintf("label=\"%s\", shape=%s, tooltip=\"gene %d\"", dotEscaper.Replace(label), shape, i)
+		if i == md.output {
+			attrs += ", style=filled, fillcolor=\"lightblue\", peripheries=2"
+		}
+		fmt.Fprintf(&b, "\tg%d [%s];\n", i, attrs)
+	}
+	for i, gene := range md.program {
+		if !active[i] || gene.op >= 0 {
+			continue
+		}
+		args := gene.args()
+		for k, adr := range args {
+			if len(args) > 1 {
+				fmt.Fprintf(&b, "\tg%d -> g%d [label=\"%d\"];\n", adr, i, k+1)
+			} else {
+				fmt.Fprintf(&b, "\tg%d -> g%d;\n", adr, i)
+			}
+		}
+	}
+	fmt.Fprintf(&b, "}\n")
+	return b.String()
+}
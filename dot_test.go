@@ -0,0 +1,34 @@
+package mep
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDot(t *testing.T) {
+
+	// x is shared by both products and the constant by both operands of the sum
+	md, err := ParseModel("(x*2)+(y*2)-x", []string{"x", "y"})
+	ok(t, err)
+	dot := md.Dot()
+
+	equals(t, true, strings.HasPrefix(dot, "digraph mep {\n"))
+	equals(t, 1, strings.Count(dot, `label="x"`))
+	equals(t, 1, strings.Count(dot, `label="2", shape`))
+	equals(t, 2, strings.Count(dot, `label="mul"`))
+	equals(t, 1, strings.Count(dot, "peripheries=2"))
+	equals(t, 8, strings.Count(dot, " -> "))
+
+	// inactive genes are left out
+	md.program = append(md.program, instruction{op: -5, adr1: 0})
+	equals(t, dot, md.Dot())
+	md.output = len(md.program) - 1
+	equals(t, `digraph mep {
+	rankdir=BT;
+	node [fontname="Helvetica"];
+	g0 [label="x", shape=box, tooltip="gene 0"];
+	g7 [label="sin", shape=circle, tooltip="gene 7", style=filled, fillcolor="lightblue", peripheries=2];
+	g0 -> g7;
+}
+`, md.Dot())
+}
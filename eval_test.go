@@ -0,0 +1,97 @@
+package mep
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+func TestFixedOutput(t *testing.T) {
+
+	m := New(NewPythagorean(50), TotalErrorFF)
+	m.SetPop(20, 2, 20)
+	m.SetFixedOutput(true)
+
+	for _, subPop := range m.pop {
+		for _, c := range subPop {
+			equals(t, m.codeLength-1, c.bestIndex)
+			equals(t, m.ff(m.model(c).Predict(m.td.Train), m.td.Target), c.fitness)
+		}
+	}
+
+	// seeds are placed at the end of the chromosome
+	ok(t, m.SetSeedExpr([]string{"sqrt(x0*x0+x1*x1)"}, 0.5))
+	equals(t, 0.0, m.pop[0][0].fitness)
+	equals(t, "sqrt(x0*x0+x1*x1)", m.BestExpr())
+
+	m.Solve(5, 0, false)
+	equals(t, m.codeLength-1, m.pop[m.bestPop][0].bestIndex)
+}
+
+func benchmarkEval(b *testing.B, fixedOutput bool) {
+	m := New(NewPythagorean(10000), TotalErrorFF)
+	m.SetPop(20, 1, 100)
+	m.SetFixedOutput(fixedOutput)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c := m.pop[0][i%m.subPopSize]
+		m.eval(m.results[0], &c)
+	}
+}
+
+func BenchmarkEval(b *testing.B) {
+	benchmarkEval(b, false)
+}
+
+func BenchmarkEvalFixedOutput(b *testing.B) {
+	benchmarkEval(b, true)
+}
+
+func TestNaNFitness(t *testing.T) {
+
+	pop := subPopulation{{fitness: math.NaN()}, {fitness: 1}, {fitness: math.NaN()}, {fitness: 0}, {fitness: math.Inf(1)}}
+	sort.Sort(pop)
+	equals(t, 0.0, pop[0].fitness)
+	equals(t, 1.0, pop[1].fitness)
+	equals(t, math.Inf(1), pop[2].fitness)
+	if !math.IsNaN(pop[3].fitness) || !math.IsNaN(pop[4].fitness) {
+		t.Errorf("NaN fitness not sorted last: %v", pop)
+	}
+
+	// a fitness function giving NaN for some of the genes
+	ff := func(signal, target []float64) float64 {
+		if signal[0] > 1 {
+			return math.NaN()
+		}
+		return TotalErrorFF(signal, target)
+	}
+	m := New(NewPythagorean(20), ff)
+	m.SetPop(20, 2, 10)
+	m.Solve(10, 0, false)
+	for _, subPop := range m.pop {
+		if !sort.IsSorted(subPop) {
+			t.Errorf("sub-population not sorted")
+		}
+	}
+	if math.IsNaN(m.BestFitness()) {
+		t.Errorf("NaN best fitness")
+	}
+
+	// non-finite outputs are invalid; without constants every output depends on the variables
+	td := NewPythagorean(20)
+	td.Train[3][0] = math.NaN()
+	td.Train[3][1] = math.NaN()
+	m = New(td, TotalErrorFF)
+	m.SetPop(20, 1, 10)
+	m.SetFixedOutput(true)
+	md, err := m.ParseExpr("x0+x1")
+	ok(t, err)
+	c := m.seedChromosome(0, md)
+	equals(t, math.Inf(1), c.fitness)
+	m.Evolve()
+	equals(t, 20, m.InvalidEvals()) // only the offspring are counted
+
+	m.SetPop(20, 2, 10)
+	m.Evolve()
+	equals(t, 40, m.InvalidEvals())
+}
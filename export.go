@@ -0,0 +1,135 @@
+package mep
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Emitter - back-end translating a model into a target language.
+// Export folds constant sub-expressions, then calls Variable, Constant and Operator
+// bottom-up over the expression tree and finally Function with the translated expression.
+// Emitters may keep state between these calls, so they should not be shared by concurrent exports.
+type Emitter interface {
+	// Variable - reference to input variable index
+	Variable(index int, labels []string) string
+	// Constant - numeric literal
+	Constant(value float64) string
+	// Operator - apply the operator (as named by Oper) to the translated arguments
+	Operator(name string, args []string) (string, error)
+	// Function - wrap the translated expression into the complete source
+	Function(expr string, labels []string) string
+}
+
+// Export - translate the model using the given back-end
+func (md *Model) Export(e Emitter) (string, error) {
+	expr, err := emitNode(e, md.Prune().tree().expand().foldConstants(), md.Labels)
+	if err != nil {
+		return "", err
+	}
+	return e.Function(expr, md.Labels), nil
+}
+
+func emitNode(e Emitter, n *node, labels []string) (string, error) {
+	switch n.kind {
+	case variableNode:
+		return e.Variable(n.op, labels), nil
+	case constantNode:
+		return e.Constant(n.value), nil
+	}
+	args := make([]string, len(n.args))
+	for i, arg := range n.args {
+		s, err := emitNode(e, arg, labels)
+		if err != nil {
+			return "", err
+		}
+		args[i] = s
+	}
+	return e.Operator(operatorOf(n.op).Name(), args)
+}
+
+// floatLiteral - shortest representation of a finite value that is always a floating point literal,
+// so that constant sub-expressions are never integer arithmetic; negative values are parenthesised
+func floatLiteral(v float64) string {
+	s := strconv.FormatFloat(v, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	if math.Signbit(v) {
+		return "(" + s + ")"
+	}
+	return s
+}
+
+// helperSet - helper functions used by an export, in order of first use
+type helperSet struct {
+	names []string
+	used  map[string]bool
+}
+
+func (h *helperSet) use(name string) {
+	if h.used == nil {
+		h.used = map[string]bool{}
+	}
+	if !h.used[name] {
+		h.used[name] = true
+		h.names = append(h.names, name)
+	}
+}
+
+func (h *helperSet) reset() {
+	h.names = nil
+	h.used = nil
+}
+
+// identifier - turn a label into a valid identifier
+func identifier(label string, keywords map[string]bool) string {
+	id := []rune(label)
+	for i, r := range id {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			id[i] = '_'
+		}
+	}
+	s := string(id)
+	if s == "" || unicode.IsDigit(id[0]) || keywords[s] {
+		s = "_" + s
+	}
+	return s
+}
+
+// identifiers - unique identifiers for the labels
+func identifiers(labels []string, keywords map[string]bool) []string {
+	var ids []string
+	used := map[string]bool{}
+	for _, label := range labels {
+		id := identifier(label, keywords)
+		for used[id] {
+			id += "_"
+		}
+		used[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// foldConstants - replace operators on constants by their value, so that
+// exported code does not depend on the target language's constant arithmetic
+func (n *node) foldConstants() *node {
+	if n.kind != operatorNode {
+		return n
+	}
+	args := make([]*node, len(n.args))
+	allConst := true
+	for i, a := range n.args {
+		args[i] = a.foldConstants()
+		if args[i].kind != constantNode {
+			allConst = false
+		}
+	}
+	n = operatorNodeOf(n.op, args...)
+	if allConst {
+		return constantNodeOf(fold(n))
+	}
+	return n
+}
@@ -0,0 +1,136 @@
+package mep
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// cKeywords - identifiers that can not be used as parameter names
+var cKeywords = map[string]bool{
+	"auto": true, "break": true, "case": true, "char": true, "const": true, "continue": true,
+	"default": true, "do": true, "double": true, "else": true, "enum": true, "extern": true,
+	"float": true, "for": true, "goto": true, "if": true, "inline": true, "int": true, "long": true,
+	"register": true, "restrict": true, "return": true, "short": true, "signed": true, "sizeof": true,
+	"static": true, "struct": true, "switch": true, "typedef": true, "union": true, "unsigned": true,
+	"void": true, "volatile": true, "while": true,
+}
+
+// cHelpers - helper functions replicating the semantics of eval exactly
+var cHelpers = map[string]string{
+	"max":    "(double a, double b) {\n\treturn a > b ? a : b;\n}",
+	"min":    "(double a, double b) {\n\treturn a < b ? a : b;\n}",
+	"square": "(double a) {\n\treturn a * a;\n}",
+	"cube":   "(double a) {\n\treturn a * a * a;\n}",
+	"gauss":  "(double a) {\n\treturn exp(-(a * a));\n}",
+	"sign":   "(double a) {\n\treturn a > 0 ? 1.0 : a < 0 ? -1.0 : a;\n}",
+	// math.Pow10(int(a)), with out of range conversions giving the minimum int as on amd64
+	"pow10": "(double a) {\n\tif (!(fabs(a) < 9.2e18)) {\n\t\treturn 0;\n\t}\n\tlong long n = (long long)a;\n" +
+		"\tif (n > 308) {\n\t\treturn INFINITY;\n\t}\n\tif (n < -323) {\n\t\treturn 0;\n\t}\n\treturn pow(10, (double)n);\n}",
+}
+
+// CEmitter - C back-end: double Name(const double *x),
+// or with parameters named after the labels when Named is set
+type CEmitter struct {
+	Name    string
+	Named   bool
+	helpers helperSet
+}
+
+func (e *CEmitter) helper(name string) string {
+	e.helpers.use(name)
+	return e.Name + "_" + name
+}
+
+// Variable - x[index] or the parameter named after the label
+func (e *CEmitter) Variable(index int, labels []string) string {
+	if !e.Named {
+		return fmt.Sprintf("x[%d]", index)
+	}
+	return identifiers(labels, cKeywords)[index]
+}
+
+// Constant - double literal
+func (e *CEmitter) Constant(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NAN"
+	case math.IsInf(v, 1):
+		return "INFINITY"
+	case math.IsInf(v, -1):
+		return "(-INFINITY)"
+	}
+	return floatLiteral(v)
+}
+
+// Operator - C expression using math.h
+func (e *CEmitter) Operator(name string, a []string) (string, error) {
+	switch name {
+	case "add":
+		return "(" + a[0] + " + " + a[1] + ")", nil
+	case "sub":
+		return "(" + a[0] + " - " + a[1] + ")", nil
+	case "mul":
+		return "(" + a[0] + " * " + a[1] + ")", nil
+	case "div":
+		return "(" + a[0] + " / " + a[1] + ")", nil
+	case "sin", "cos", "tan", "exp", "log", "sqrt", "log10", "log2", "floor", "ceil", "tanh", "atan", "cbrt":
+		return name + "(" + a[0] + ")", nil
+	case "atan2", "fmod", "hypot":
+		return name + "(" + a[0] + ", " + a[1] + ")", nil
+	case "abs":
+		return "fabs(" + a[0] + ")", nil
+	case "max", "min":
+		return e.helper(name) + "(" + a[0] + ", " + a[1] + ")", nil
+	case "ifgtz":
+		return "(" + a[0] + " > 0 ? " + a[1] + " : " + a[2] + ")", nil
+	case "ifltz":
+		return "(" + a[0] + " < 0 ? " + a[1] + " : " + a[2] + ")", nil
+	case "ifgt":
+		return "(" + a[0] + " > " + a[1] + " ? " + a[2] + " : " + a[3] + ")", nil
+	case "iflt":
+		return "(" + a[0] + " < " + a[1] + " ? " + a[2] + " : " + a[3] + ")", nil
+	case "ifbgt":
+		return "(" + a[0] + " > " + a[1] + " ? 1.0 : 0.0)", nil
+	case "ifblt":
+		return "(" + a[0] + " < " + a[1] + " ? 1.0 : 0.0)", nil
+	case "and":
+		return "(" + a[0] + " > 0 && " + a[1] + " > 0 ? 1.0 : 0.0)", nil
+	case "or":
+		return "(" + a[0] + " > 0 || " + a[1] + " > 0 ? 1.0 : 0.0)", nil
+	case "pow":
+		return "pow(" + a[0] + ", " + a[1] + ")", nil
+	case "pow10", "square", "cube", "gauss", "sign":
+		return e.helper(name) + "(" + a[0] + ")", nil
+	case "inv":
+		return "(1.0 / " + a[0] + ")", nil
+	case "sigmoid":
+		return "(1.0 / (1.0 + exp(-" + a[0] + ")))", nil
+	case "neg":
+		return "(-" + a[0] + ")", nil
+	case "step":
+		return "(" + a[0] + " > 0 ? 1.0 : 0.0)", nil
+	}
+	return formatOperator("c", name, a)
+}
+
+// Function - the source file
+func (e *CEmitter) Function(expr string, labels []string) string {
+
+	params := "const double *x"
+	if e.Named {
+		params = "double " + strings.Join(identifiers(labels, cKeywords), ", double ")
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "/* Code generated by go-mep. DO NOT EDIT. */\n\n#include <math.h>\n\n")
+	for _, h := range e.helpers.names {
+		fmt.Fprintf(&b, "static double %s_%s%s\n\n", e.Name, h, cHelpers[h])
+	}
+	fmt.Fprintf(&b, "/* %s - computes the evolved model */\n", e.Name)
+	fmt.Fprintf(&b, "double %s(%s) {\n\treturn %s;\n}\n", e.Name, params, expr)
+
+	e.helpers.reset()
+	return b.String()
+}
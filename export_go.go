@@ -0,0 +1,210 @@
+package mep
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// goKeywords - identifiers that can not be used as parameter names
+var goKeywords = map[string]bool{
+	"break": true, "case": true, "chan": true, "const": true, "continue": true, "default": true,
+	"defer": true, "else": true, "fallthrough": true, "for": true, "func": true, "go": true,
+	"goto": true, "if": true, "import": true, "interface": true, "map": true, "package": true,
+	"range": true, "return": true, "select": true, "struct": true, "switch": true, "type": true,
+	"var": true, "math": true, "int": true, "float64": true, "bool": true, "true": true, "false": true,
+	"nil": true,
+}
+
+// goHelpers - helper functions replicating the semantics of eval exactly
+var goHelpers = map[string]string{
+	"If":     "(c bool, a, b float64) float64 {\n\tif c {\n\t\treturn a\n\t}\n\treturn b\n}",
+	"Max":    "(a, b float64) float64 {\n\tif a > b {\n\t\treturn a\n\t}\n\treturn b\n}",
+	"Min":    "(a, b float64) float64 {\n\tif a < b {\n\t\treturn a\n\t}\n\treturn b\n}",
+	"Square": "(a float64) float64 {\n\treturn a * a\n}",
+	"Cube":   "(a float64) float64 {\n\treturn a * a * a\n}",
+	"Gauss":  "(a float64) float64 {\n\treturn math.Exp(-(a * a))\n}",
+	"Sign":   "(a float64) float64 {\n\tswitch {\n\tcase a > 0:\n\t\treturn 1\n\tcase a < 0:\n\t\treturn -1\n\t}\n\treturn a\n}",
+}
+
+// GoEmitter - Go back-end: a source file with func Name(x []float64) float64 (Model if Name is empty),
+// or with parameters named after the labels when Named is set
+type GoEmitter struct {
+	Package string
+	Name    string
+	Named   bool
+	helpers helperSet
+	math    bool
+}
+
+// ExportGo - Go source file (package pkg) with a function computing the model
+func (md *Model) ExportGo(pkg, name string, named bool) string {
+	src, err := md.Export(&GoEmitter{Package: pkg, Name: name, Named: named})
+	if err != nil {
+		panic(err)
+	}
+	return src
+}
+
+// name - the function name, Model by default
+func (e *GoEmitter) name() string {
+	if e.Name == "" {
+		return "Model"
+	}
+	return e.Name
+}
+
+func (e *GoEmitter) helper(name string) string {
+	e.helpers.use(name)
+	return strings.ToLower(e.name()[:1]) + e.name()[1:] + name
+}
+
+func (e *GoEmitter) call(fn string, args ...string) string {
+	if strings.HasPrefix(fn, "math.") {
+		e.math = true
+	}
+	return fn + "(" + strings.Join(args, ", ") + ")"
+}
+
+// Variable - x[index] or the parameter named after the label
+func (e *GoEmitter) Variable(index int, labels []string) string {
+	if !e.Named {
+		return fmt.Sprintf("x[%d]", index)
+	}
+	return identifiers(labels, goKeywords)[index]
+}
+
+// Constant - float literal
+func (e *GoEmitter) Constant(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return e.call("math.NaN")
+	case math.IsInf(v, 1):
+		return e.call("math.Inf", "1")
+	case math.IsInf(v, -1):
+		return e.call("math.Inf", "-1")
+	}
+	return floatLiteral(v)
+}
+
+// Operator - Go expression using the math package
+func (e *GoEmitter) Operator(name string, a []string) (string, error) {
+	switch name {
+	case "add":
+		return "(" + a[0] + " + " + a[1] + ")", nil
+	case "sub":
+		return "(" + a[0] + " - " + a[1] + ")", nil
+	case "mul":
+		return "(" + a[0] + " * " + a[1] + ")", nil
+	case "div":
+		if a[1] == e.Constant(0) || a[1] == e.Constant(math.Copysign(0, -1)) { // division by a constant zero does not compile
+			a[1] = e.call("math.Copysign", "0", a[1])
+		}
+		return "(" + a[0] + " / " + a[1] + ")", nil
+	case "sin":
+		return e.call("math.Sin", a[0]), nil
+	case "cos":
+		return e.call("math.Cos", a[0]), nil
+	case "tan":
+		return e.call("math.Tan", a[0]), nil
+	case "exp":
+		return e.call("math.Exp", a[0]), nil
+	case "log":
+		return e.call("math.Log", a[0]), nil
+	case "sqrt":
+		return e.call("math.Sqrt", a[0]), nil
+	case "abs":
+		return e.call("math.Abs", a[0]), nil
+	case "max":
+		return e.call(e.helper("Max"), a[0], a[1]), nil
+	case "min":
+		return e.call(e.helper("Min"), a[0], a[1]), nil
+	case "ifgtz":
+		return e.call(e.helper("If"), a[0]+" > 0", a[1], a[2]), nil
+	case "ifltz":
+		return e.call(e.helper("If"), a[0]+" < 0", a[1], a[2]), nil
+	case "ifgt":
+		return e.call(e.helper("If"), a[0]+" > "+a[1], a[2], a[3]), nil
+	case "iflt":
+		return e.call(e.helper("If"), a[0]+" < "+a[1], a[2], a[3]), nil
+	case "ifbgt":
+		return e.call(e.helper("If"), a[0]+" > "+a[1], "1", "0"), nil
+	case "ifblt":
+		return e.call(e.helper("If"), a[0]+" < "+a[1], "1", "0"), nil
+	case "and":
+		return e.call(e.helper("If"), a[0]+" > 0 && "+a[1]+" > 0", "1", "0"), nil
+	case "or":
+		return e.call(e.helper("If"), a[0]+" > 0 || "+a[1]+" > 0", "1", "0"), nil
+	case "pow":
+		return e.call("math.Pow", a[0], a[1]), nil
+	case "pow10":
+		return e.call("math.Pow10", "int("+a[0]+")"), nil
+	case "log10":
+		return e.call("math.Log10", a[0]), nil
+	case "log2":
+		return e.call("math.Log2", a[0]), nil
+	case "floor":
+		return e.call("math.Floor", a[0]), nil
+	case "ceil":
+		return e.call("math.Ceil", a[0]), nil
+	case "inv":
+		return "(1 / " + a[0] + ")", nil
+	case "square":
+		return e.call(e.helper("Square"), a[0]), nil
+	case "tanh":
+		return e.call("math.Tanh", a[0]), nil
+	case "sigmoid":
+		return "(1 / (1 + " + e.call("math.Exp", "-"+a[0]) + "))", nil
+	case "atan":
+		return e.call("math.Atan", a[0]), nil
+	case "atan2":
+		return e.call("math.Atan2", a[0], a[1]), nil
+	case "fmod":
+		return e.call("math.Mod", a[0], a[1]), nil
+	case "hypot":
+		return e.call("math.Hypot", a[0], a[1]), nil
+	case "neg":
+		return "(-" + a[0] + ")", nil
+	case "cube":
+		return e.call(e.helper("Cube"), a[0]), nil
+	case "cbrt":
+		return e.call("math.Cbrt", a[0]), nil
+	case "gauss":
+		e.math = true
+		return e.call(e.helper("Gauss"), a[0]), nil
+	case "sign":
+		return e.call(e.helper("Sign"), a[0]), nil
+	case "step":
+		return e.call(e.helper("If"), a[0]+" > 0", "1", "0"), nil
+	}
+	s, err := formatOperator("go", name, a)
+	if strings.Contains(s, "math.") {
+		e.math = true
+	}
+	return s, err
+}
+
+// Function - the source file
+func (e *GoEmitter) Function(expr string, labels []string) string {
+
+	signature := "x []float64"
+	if e.Named {
+		signature = strings.Join(identifiers(labels, goKeywords), ", ") + " float64"
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by go-mep. DO NOT EDIT.\n\npackage %s\n\n", e.Package)
+	if e.math {
+		fmt.Fprintf(&b, "import \"math\"\n\n")
+	}
+	fmt.Fprintf(&b, "// %s - computes the evolved model\n", e.name())
+	fmt.Fprintf(&b, "func %s(%s) float64 {\n\treturn %s\n}\n", e.name(), signature, expr)
+	for _, h := range e.helpers.names {
+		fmt.Fprintf(&b, "\nfunc %s%s\n", e.helper(h), goHelpers[h])
+	}
+
+	e.helpers.reset()
+	e.math = false
+	return b.String()
+}
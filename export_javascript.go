@@ -0,0 +1,134 @@
+package mep
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// jsKeywords - identifiers that can not be used as parameter names
+var jsKeywords = map[string]bool{
+	"break": true, "case": true, "catch": true, "class": true, "const": true, "continue": true,
+	"debugger": true, "default": true, "delete": true, "do": true, "else": true, "enum": true,
+	"export": true, "extends": true, "false": true, "finally": true, "for": true, "function": true,
+	"if": true, "import": true, "in": true, "instanceof": true, "let": true, "new": true, "null": true,
+	"return": true, "super": true, "switch": true, "this": true, "throw": true, "true": true, "try": true,
+	"typeof": true, "var": true, "void": true, "while": true, "with": true, "yield": true,
+	"Math": true, "NaN": true, "Infinity": true, "undefined": true,
+}
+
+// jsHelpers - helper functions replicating the semantics of eval exactly
+var jsHelpers = map[string]string{
+	"max":    "(a, b) {\n  return a > b ? a : b;\n}",
+	"min":    "(a, b) {\n  return a < b ? a : b;\n}",
+	"square": "(a) {\n  return a * a;\n}",
+	"cube":   "(a) {\n  return a * a * a;\n}",
+	"gauss":  "(a) {\n  return Math.exp(-(a * a));\n}",
+	// unlike Math.pow, math.Pow(1, NaN) and math.Pow(-1, ±Inf) are 1
+	"pow": "(a, b) {\n  if (a === 1 || (a === -1 && Math.abs(b) === Infinity)) {\n    return 1;\n  }\n  return Math.pow(a, b);\n}",
+	// math.Pow10(int(a)), with out of range conversions giving the minimum int as on amd64
+	"pow10": "(a) {\n  const n = Math.abs(a) < 9.2e18 ? Math.trunc(a) : -Math.pow(2, 63);\n" +
+		"  if (n > 308) {\n    return Infinity;\n  }\n  if (n < -323) {\n    return 0;\n  }\n  return Number('1e' + n);\n}",
+}
+
+// JSEmitter - JavaScript back-end: function Name(x), or with parameters named after the labels when Named is set
+type JSEmitter struct {
+	Name    string
+	Named   bool
+	helpers helperSet
+}
+
+func (e *JSEmitter) helper(name string, args ...string) string {
+	e.helpers.use(name)
+	return e.Name + "_" + name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// Variable - x[index] or the parameter named after the label
+func (e *JSEmitter) Variable(index int, labels []string) string {
+	if !e.Named {
+		return fmt.Sprintf("x[%d]", index)
+	}
+	return identifiers(labels, jsKeywords)[index]
+}
+
+// Constant - number literal
+func (e *JSEmitter) Constant(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "Infinity"
+	case math.IsInf(v, -1):
+		return "(-Infinity)"
+	}
+	return floatLiteral(v)
+}
+
+// Operator - JavaScript expression using Math
+func (e *JSEmitter) Operator(name string, a []string) (string, error) {
+	switch name {
+	case "add":
+		return "(" + a[0] + " + " + a[1] + ")", nil
+	case "sub":
+		return "(" + a[0] + " - " + a[1] + ")", nil
+	case "mul":
+		return "(" + a[0] + " * " + a[1] + ")", nil
+	case "div":
+		return "(" + a[0] + " / " + a[1] + ")", nil
+	case "sin", "cos", "tan", "exp", "log", "sqrt", "abs", "log10", "log2", "floor", "ceil",
+		"tanh", "atan", "cbrt", "sign":
+		return "Math." + name + "(" + a[0] + ")", nil
+	case "atan2", "hypot":
+		return "Math." + name + "(" + a[0] + ", " + a[1] + ")", nil
+	case "max", "min", "pow", "pow10", "square", "cube", "gauss":
+		return e.helper(name, a...), nil
+	case "fmod":
+		return "(" + a[0] + " % " + a[1] + ")", nil
+	case "sigmoid":
+		return "(1 / (1 + Math.exp(-" + a[0] + ")))", nil
+	case "neg":
+		return "(-" + a[0] + ")", nil
+	case "step":
+		return "(" + a[0] + " > 0 ? 1 : 0)", nil
+	case "ifgtz":
+		return "(" + a[0] + " > 0 ? " + a[1] + " : " + a[2] + ")", nil
+	case "ifltz":
+		return "(" + a[0] + " < 0 ? " + a[1] + " : " + a[2] + ")", nil
+	case "ifgt":
+		return "(" + a[0] + " > " + a[1] + " ? " + a[2] + " : " + a[3] + ")", nil
+	case "iflt":
+		return "(" + a[0] + " < " + a[1] + " ? " + a[2] + " : " + a[3] + ")", nil
+	case "ifbgt":
+		return "(" + a[0] + " > " + a[1] + " ? 1 : 0)", nil
+	case "ifblt":
+		return "(" + a[0] + " < " + a[1] + " ? 1 : 0)", nil
+	case "and":
+		return "(" + a[0] + " > 0 && " + a[1] + " > 0 ? 1 : 0)", nil
+	case "or":
+		return "(" + a[0] + " > 0 || " + a[1] + " > 0 ? 1 : 0)", nil
+	case "inv":
+		return "(1 / " + a[0] + ")", nil
+	}
+	return formatOperator("javascript", name, a)
+}
+
+// Function - the source file
+func (e *JSEmitter) Function(expr string, labels []string) string {
+
+	params := "x"
+	if e.Named {
+		params = strings.Join(identifiers(labels, jsKeywords), ", ")
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by go-mep. DO NOT EDIT.\n\n")
+	for _, h := range e.helpers.names {
+		fmt.Fprintf(&b, "function %s_%s%s\n\n", e.Name, h, jsHelpers[h])
+	}
+	fmt.Fprintf(&b, "// %s - computes the evolved model\n", e.Name)
+	fmt.Fprintf(&b, "function %s(%s) {\n  return %s;\n}\n", e.Name, params, expr)
+
+	e.helpers.reset()
+	return b.String()
+}
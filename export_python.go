@@ -0,0 +1,230 @@
+package mep
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// pythonKeywords - identifiers that can not be used as parameter names
+var pythonKeywords = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true, "assert": true, "async": true,
+	"await": true, "break": true, "class": true, "continue": true, "def": true, "del": true, "elif": true,
+	"else": true, "except": true, "finally": true, "for": true, "from": true, "global": true, "if": true,
+	"import": true, "in": true, "is": true, "lambda": true, "nonlocal": true, "not": true, "or": true,
+	"pass": true, "raise": true, "return": true, "try": true, "while": true, "with": true, "yield": true,
+	"math": true, "np": true, "x": true,
+}
+
+// pythonMathHelpers - helpers giving the math module the IEEE semantics of eval instead of exceptions
+var pythonMathHelpers = map[string]string{
+	"call": "(f, a):\n    try:\n        return f(a)\n    except (ValueError, OverflowError):\n        return math.nan",
+	"div": "(a, b):\n    try:\n        return a / b\n    except ZeroDivisionError:\n" +
+		"        if a == 0 or a != a:\n            return math.nan\n" +
+		"        return math.copysign(math.inf, a) * math.copysign(1, b)",
+	"log":  "(f, a):\n    if a > 0:\n        return f(a)\n    if a == 0:\n        return -math.inf\n    return math.nan",
+	"sqrt": "(a):\n    return math.sqrt(a) if a >= 0 else math.nan",
+	"exp":  "(a):\n    try:\n        return math.exp(a)\n    except OverflowError:\n        return math.inf",
+	"pow": "(a, b):\n    try:\n        return math.pow(a, b)\n    except OverflowError:\n" +
+		"        if a < 0 and b == int(b) and int(b) % 2 == 1:\n            return -math.inf\n        return math.inf\n" +
+		"    except ValueError:\n        if a == 0:\n" +
+		"            if b == int(b) and int(b) % 2 == 1:\n                return math.copysign(math.inf, a)\n" +
+		"            return math.inf\n        return math.nan",
+	// math.Pow10(int(a)), with out of range conversions giving the minimum int as on amd64
+	"pow10": "(a):\n    n = math.trunc(a) if math.isfinite(a) and abs(a) < 9.2e18 else -2**63\n" +
+		"    if n > 308:\n        return math.inf\n    if n < -323:\n        return 0.0\n    return float('1e%d' % n)",
+	"round":   "(f, a):\n    return float(f(a)) if math.isfinite(a) else a",
+	"max":     "(a, b):\n    return a if a > b else b",
+	"min":     "(a, b):\n    return a if a < b else b",
+	"square":  "(a):\n    return a * a",
+	"cube":    "(a):\n    return a * a * a",
+	"gauss":   "(a):\n    return math.exp(-(a * a))",
+	"sigmoid": "(a):\n    try:\n        return 1.0 / (1.0 + math.exp(-a))\n    except OverflowError:\n        return 0.0",
+	"fmod":    "(a, b):\n    try:\n        return math.fmod(a, b)\n    except ValueError:\n        return math.nan",
+	"cbrt":    "(a):\n    return math.copysign(abs(a) ** (1.0 / 3.0), a)",
+	"sign":    "(a):\n    return 1.0 if a > 0 else -1.0 if a < 0 else a",
+}
+
+// pythonNumpyHelpers - helpers for the vectorised numpy version
+var pythonNumpyHelpers = map[string]string{
+	"max": "(a, b):\n    return np.where(a > b, a, b)",
+	"min": "(a, b):\n    return np.where(a < b, a, b)",
+	// math.Pow10(int(a)), with out of range conversions giving the minimum int as on amd64
+	"pow10": "(a):\n    n = np.where(np.abs(a) < 9.2e18, np.trunc(a), -2.0**63)\n" +
+		"    return np.where(n > 308, np.inf, np.where(n < -323, 0.0, np.power(10.0, np.clip(n, -323, 308))))",
+	"cube":  "(a):\n    return a * a * a",
+	"gauss": "(a):\n    return np.exp(-(a * a))",
+}
+
+// PythonEmitter - Python back-end: def Name(x), or with parameters named after the labels when Named is set.
+// By default only the math module is used; with Numpy the function is vectorised
+// (x may be a matrix with one row per sample, or the named parameters arrays).
+type PythonEmitter struct {
+	Name    string
+	Named   bool
+	Numpy   bool
+	helpers helperSet
+}
+
+func (e *PythonEmitter) helper(name string, args ...string) string {
+	e.helpers.use(name)
+	return "_" + e.Name + "_" + name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// Variable - x[index] or the parameter named after the label
+func (e *PythonEmitter) Variable(index int, labels []string) string {
+	if !e.Named {
+		if e.Numpy {
+			return fmt.Sprintf("x[..., %d]", index)
+		}
+		return fmt.Sprintf("x[%d]", index)
+	}
+	return identifiers(labels, pythonKeywords)[index]
+}
+
+// Constant - float literal
+func (e *PythonEmitter) Constant(v float64) string {
+	prefix := "math."
+	if e.Numpy {
+		prefix = "np."
+	}
+	switch {
+	case math.IsNaN(v):
+		return prefix + "nan"
+	case math.IsInf(v, 1):
+		return prefix + "inf"
+	case math.IsInf(v, -1):
+		return "(-" + prefix + "inf)"
+	}
+	return floatLiteral(v)
+}
+
+// Operator - Python expression
+func (e *PythonEmitter) Operator(name string, a []string) (string, error) {
+	switch name {
+	case "add":
+		return "(" + a[0] + " + " + a[1] + ")", nil
+	case "sub":
+		return "(" + a[0] + " - " + a[1] + ")", nil
+	case "mul":
+		return "(" + a[0] + " * " + a[1] + ")", nil
+	}
+	if e.Numpy {
+		return e.numpy(name, a)
+	}
+
+	switch name {
+	case "div":
+		return e.helper("div", a[0], a[1]), nil
+	case "sin", "cos", "tan":
+		return e.helper("call", "math."+name, a[0]), nil
+	case "exp", "sqrt", "pow", "pow10", "max", "min", "square", "cube", "gauss", "sigmoid", "fmod", "cbrt", "sign":
+		return e.helper(name, a...), nil
+	case "tanh", "atan":
+		return "math." + name + "(" + a[0] + ")", nil
+	case "atan2", "hypot":
+		return "math." + name + "(" + a[0] + ", " + a[1] + ")", nil
+	case "neg":
+		return "(-" + a[0] + ")", nil
+	case "log", "log10", "log2":
+		return e.helper("log", "math."+name, a[0]), nil
+	case "abs":
+		return "abs(" + a[0] + ")", nil
+	case "floor", "ceil":
+		return e.helper("round", "math."+name, a[0]), nil
+	case "inv":
+		return e.helper("div", "1.0", a[0]), nil
+	}
+	return e.conditional(name, a, "(%[2]s if %[1]s else %[3]s)", " and ", " or ")
+}
+
+func (e *PythonEmitter) numpy(name string, a []string) (string, error) {
+	switch name {
+	case "div":
+		return "np.divide(" + a[0] + ", " + a[1] + ")", nil
+	case "sin", "cos", "tan", "exp", "log", "sqrt", "log10", "log2", "floor", "ceil", "abs", "square":
+		return "np." + name + "(" + a[0] + ")", nil
+	case "pow":
+		return "np.power(" + a[0] + ", " + a[1] + ")", nil
+	case "pow10", "max", "min":
+		return e.helper(name, a...), nil
+	case "inv":
+		return "np.divide(1.0, " + a[0] + ")", nil
+	case "tanh", "cbrt", "sign", "fmod", "hypot":
+		return "np." + name + "(" + strings.Join(a, ", ") + ")", nil
+	case "atan", "atan2":
+		return "np.arc" + name[1:] + "(" + strings.Join(a, ", ") + ")", nil
+	case "sigmoid":
+		return "(1.0 / (1.0 + np.exp(-" + a[0] + ")))", nil
+	case "neg":
+		return "(-" + a[0] + ")", nil
+	case "cube", "gauss":
+		return e.helper(name, a...), nil
+	}
+	return e.conditional(name, a, "np.where(%[1]s, %[2]s, %[3]s)", " & ", " | ")
+}
+
+// conditional - the if* operators, and, or
+func (e *PythonEmitter) conditional(name string, a []string, format, and, or string) (string, error) {
+	switch name {
+	case "ifgtz":
+		return fmt.Sprintf(format, "("+a[0]+" > 0)", a[1], a[2]), nil
+	case "ifltz":
+		return fmt.Sprintf(format, "("+a[0]+" < 0)", a[1], a[2]), nil
+	case "ifgt":
+		return fmt.Sprintf(format, "("+a[0]+" > "+a[1]+")", a[2], a[3]), nil
+	case "iflt":
+		return fmt.Sprintf(format, "("+a[0]+" < "+a[1]+")", a[2], a[3]), nil
+	case "ifbgt":
+		return fmt.Sprintf(format, "("+a[0]+" > "+a[1]+")", "1.0", "0.0"), nil
+	case "ifblt":
+		return fmt.Sprintf(format, "("+a[0]+" < "+a[1]+")", "1.0", "0.0"), nil
+	case "and":
+		return fmt.Sprintf(format, "("+a[0]+" > 0)"+and+"("+a[1]+" > 0)", "1.0", "0.0"), nil
+	case "or":
+		return fmt.Sprintf(format, "("+a[0]+" > 0)"+or+"("+a[1]+" > 0)", "1.0", "0.0"), nil
+	case "step":
+		return fmt.Sprintf(format, "("+a[0]+" > 0)", "1.0", "0.0"), nil
+	}
+	if e.Numpy {
+		return formatOperator("numpy", name, a)
+	}
+	return formatOperator("python", name, a)
+}
+
+// Function - the source module
+func (e *PythonEmitter) Function(expr string, labels []string) string {
+
+	params := "x"
+	if e.Named {
+		params = strings.Join(identifiers(labels, pythonKeywords), ", ")
+	}
+	helpers := pythonMathHelpers
+	if e.Numpy {
+		helpers = pythonNumpyHelpers
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# Code generated by go-mep. DO NOT EDIT.\n\n")
+	if e.Numpy {
+		fmt.Fprintf(&b, "import numpy as np\n\n")
+	} else {
+		fmt.Fprintf(&b, "import math\n\n")
+	}
+	for _, h := range e.helpers.names {
+		fmt.Fprintf(&b, "\ndef _%s_%s%s\n\n", e.Name, h, helpers[h])
+	}
+	fmt.Fprintf(&b, "\ndef %s(%s):\n    \"\"\"computes the evolved model\"\"\"\n", e.Name, params)
+	if e.Numpy {
+		if !e.Named {
+			fmt.Fprintf(&b, "    x = np.asarray(x, dtype=np.float64)\n")
+		}
+		fmt.Fprintf(&b, "    with np.errstate(all='ignore'):\n        return %s\n", expr)
+	} else {
+		fmt.Fprintf(&b, "    return %s\n", expr)
+	}
+
+	e.helpers.reset()
+	return b.String()
+}
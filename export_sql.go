@@ -0,0 +1,106 @@
+package mep
+
+import (
+	"math"
+	"strings"
+)
+
+// SQLEmitter - ANSI SQL back-end: a single expression over the columns named by the labels,
+// using CASE WHEN for the conditional operators. SQL has no NaN or infinity, so non-finite
+// constants become NULL and the result of a domain error depends on the database.
+type SQLEmitter struct{}
+
+// Variable - quoted column name
+func (e *SQLEmitter) Variable(index int, labels []string) string {
+	return `"` + strings.Replace(labels[index], `"`, `""`, -1) + `"`
+}
+
+// Constant - numeric literal
+func (e *SQLEmitter) Constant(v float64) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return "NULL"
+	}
+	return floatLiteral(v)
+}
+
+func caseWhen(cond, a, b string) string {
+	return "CASE WHEN " + cond + " THEN " + a + " ELSE " + b + " END"
+}
+
+// Operator - SQL expression
+func (e *SQLEmitter) Operator(name string, a []string) (string, error) {
+	switch name {
+	case "add":
+		return "(" + a[0] + " + " + a[1] + ")", nil
+	case "sub":
+		return "(" + a[0] + " - " + a[1] + ")", nil
+	case "mul":
+		return "(" + a[0] + " * " + a[1] + ")", nil
+	case "div":
+		return "(" + a[0] + " / " + a[1] + ")", nil
+	case "sin", "cos", "tan", "exp", "sqrt", "abs", "floor", "log10":
+		return strings.ToUpper(name) + "(" + a[0] + ")", nil
+	case "log":
+		return "LN(" + a[0] + ")", nil
+	case "log2":
+		return "(LN(" + a[0] + ") / LN(2.0))", nil
+	case "ceil":
+		return "CEILING(" + a[0] + ")", nil
+	case "max":
+		return caseWhen(a[0]+" > "+a[1], a[0], a[1]), nil
+	case "min":
+		return caseWhen(a[0]+" < "+a[1], a[0], a[1]), nil
+	case "ifgtz":
+		return caseWhen(a[0]+" > 0", a[1], a[2]), nil
+	case "ifltz":
+		return caseWhen(a[0]+" < 0", a[1], a[2]), nil
+	case "ifgt":
+		return caseWhen(a[0]+" > "+a[1], a[2], a[3]), nil
+	case "iflt":
+		return caseWhen(a[0]+" < "+a[1], a[2], a[3]), nil
+	case "ifbgt":
+		return caseWhen(a[0]+" > "+a[1], "1.0", "0.0"), nil
+	case "ifblt":
+		return caseWhen(a[0]+" < "+a[1], "1.0", "0.0"), nil
+	case "and":
+		return caseWhen(a[0]+" > 0 AND "+a[1]+" > 0", "1.0", "0.0"), nil
+	case "or":
+		return caseWhen(a[0]+" > 0 OR "+a[1]+" > 0", "1.0", "0.0"), nil
+	case "pow":
+		return "POWER(" + a[0] + ", " + a[1] + ")", nil
+	case "pow10":
+		return "POWER(10.0, " + caseWhen(a[0]+" < 0", "CEILING("+a[0]+")", "FLOOR("+a[0]+")") + ")", nil
+	case "inv":
+		return "(1.0 / " + a[0] + ")", nil
+	case "square":
+		return "(" + a[0] + " * " + a[0] + ")", nil
+	case "tanh", "atan":
+		return strings.ToUpper(name) + "(" + a[0] + ")", nil
+	case "atan2":
+		return "ATAN2(" + a[0] + ", " + a[1] + ")", nil
+	case "fmod":
+		return "MOD(" + a[0] + ", " + a[1] + ")", nil
+	case "hypot":
+		return "SQRT(" + a[0] + " * " + a[0] + " + " + a[1] + " * " + a[1] + ")", nil
+	case "sigmoid":
+		return "(1.0 / (1.0 + EXP(-" + a[0] + ")))", nil
+	case "neg":
+		return "(-" + a[0] + ")", nil
+	case "cube":
+		return "(" + a[0] + " * " + a[0] + " * " + a[0] + ")", nil
+	case "cbrt":
+		return caseWhen(a[0]+" < 0", "(-POWER(-"+a[0]+", 1.0 / 3.0))", "POWER("+a[0]+", 1.0 / 3.0)"), nil
+	case "gauss":
+		return "EXP(-(" + a[0] + " * " + a[0] + "))", nil
+	case "sign":
+		return caseWhen(a[0]+" > 0", "1.0", caseWhen(a[0]+" < 0", "-1.0", a[0])), nil
+	case "step":
+		return caseWhen(a[0]+" > 0", "1.0", "0.0"), nil
+	}
+	return formatOperator("sql", name, a)
+}
+
+// Function - the expression itself
+func (e *SQLEmitter) Function(expr string, labels []string) string {
+	return expr
+}
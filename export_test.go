@@ -0,0 +1,248 @@
+package mep
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// randomModels - models of the last gene of random chromosomes using every builtin operator.
+// The inputs are kept small, as languages disagree on trigonometric functions of huge arguments.
+func randomModels(numModels int) (*Mep, []*Model) {
+	testdata := testData{
+		xmin: -2,
+		xmax: 2,
+		eval: func(terms []float64) float64 {
+			return terms[0] * terms[1]
+		},
+	}
+	m := New(testdata.generate(10, 2), TotalErrorFF)
+	for _, o := range builtinOperators {
+		m.SetOper(o.Name(), true)
+	}
+	m.SetConst([]float64{0, 2}, 3, -2, 2)
+	m.SetPop(numModels, 1, 10)
+	var models []*Model
+	for _, c := range m.pop[0] {
+		md := m.model(c)
+		md.output = len(md.program) - 1
+		models = append(models, md)
+	}
+	return m, models
+}
+
+// runExported - write the files into a temporary directory, run the command there and
+// check that it prints the predictions of every model on every row, one per line
+func runExported(t *testing.T, files map[string]string, models []*Model, data [][]float64, command ...string) {
+
+	if _, err := exec.LookPath(command[0]); err != nil {
+		t.Skipf("%s not found", command[0])
+	}
+
+	dir, err := ioutil.TempDir("", "mep")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	for name, src := range files {
+		ok(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644))
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GO111MODULE=off")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s\n%s", err, out)
+	}
+
+	lines := strings.Fields(string(out))
+	equals(t, len(models)*len(data), len(lines))
+	for i, md := range models {
+		for k, expected := range md.Predict(data) {
+			line := lines[i*len(data)+k]
+			if line == "-nan" { // printf in C
+				line = "nan"
+			}
+			actual, err := strconv.ParseFloat(line, 64)
+			ok(t, err)
+			if !sameValue(expected, actual) {
+				t.Errorf("%s: row %d: expected %v, got %v", md, k, expected, actual)
+			}
+		}
+	}
+}
+
+func TestExportGo(t *testing.T) {
+
+	// the function is named Model by default
+	md, err := ParseModel("square(x0)", []string{"x0"})
+	ok(t, err)
+	equals(t, true, strings.Contains(md.ExportGo("main", "", false), "func Model(x []float64) float64"))
+
+	m, models := randomModels(20)
+
+	files := map[string]string{}
+	var main bytes.Buffer
+	fmt.Fprintf(&main, "package main\n\nimport \"fmt\"\n\nfunc main() {\n")
+	for i, md := range models {
+		name := fmt.Sprintf("Model%d", i)
+		named := i%2 == 1
+		files[strings.ToLower(name)+".go"] = md.ExportGo("main", name, named)
+		for _, row := range m.td.Train {
+			if named {
+				fmt.Fprintf(&main, "\tfmt.Println(%s(%#v, %#v))\n", name, row[0], row[1])
+			} else {
+				fmt.Fprintf(&main, "\tfmt.Println(%s(%#v))\n", name, row)
+			}
+		}
+	}
+	fmt.Fprintf(&main, "}\n")
+	files["main.go"] = main.String()
+
+	args := []string{"go", "run"}
+	for name := range files {
+		args = append(args, name)
+	}
+	runExported(t, files, models, m.td.Train, args...)
+}
+
+func TestExportC(t *testing.T) {
+
+	m, models := randomModels(20)
+
+	files := map[string]string{}
+	var main bytes.Buffer
+	fmt.Fprintf(&main, "#include <stdio.h>\n\n")
+	for i, md := range models {
+		name := fmt.Sprintf("model%d", i)
+		named := i%2 == 1
+		src, err := md.Export(&CEmitter{Name: name, Named: named})
+		ok(t, err)
+		files[name+".c"] = src
+		if named {
+			fmt.Fprintf(&main, "double %s(double, double);\n", name)
+		} else {
+			fmt.Fprintf(&main, "double %s(const double *);\n", name)
+		}
+	}
+	fmt.Fprintf(&main, "\nint main() {\n")
+	for i := range models {
+		for _, row := range m.td.Train {
+			if i%2 == 1 {
+				fmt.Fprintf(&main, "\tprintf(\"%%.17g\\n\", model%d(%v, %v));\n", i, row[0], row[1])
+			} else {
+				fmt.Fprintf(&main, "\t{\n\t\tdouble x[] = {%v, %v};\n\t\tprintf(\"%%.17g\\n\", model%d(x));\n\t}\n", row[0], row[1], i)
+			}
+		}
+	}
+	fmt.Fprintf(&main, "\treturn 0;\n}\n")
+	files["main.c"] = main.String()
+
+	args := []string{"sh", "-c", "cc -o model *.c -lm && ./model"}
+	if _, err := exec.LookPath("cc"); err != nil {
+		t.Skip("cc not found")
+	}
+	runExported(t, files, models, m.td.Train, args...)
+}
+
+func testExportPython(t *testing.T, numpy bool) {
+
+	m, models := randomModels(20)
+
+	var src bytes.Buffer
+	for i, md := range models {
+		s, err := md.Export(&PythonEmitter{Name: fmt.Sprintf("model%d", i), Named: i%2 == 1, Numpy: numpy})
+		ok(t, err)
+		src.WriteString(s + "\n")
+	}
+	for i := range models {
+		for _, row := range m.td.Train {
+			if i%2 == 1 {
+				fmt.Fprintf(&src, "print(repr(float(model%d(%v, %v))))\n", i, row[0], row[1])
+			} else {
+				fmt.Fprintf(&src, "print(repr(float(model%d([%v, %v]))))\n", i, row[0], row[1])
+			}
+		}
+	}
+	runExported(t, map[string]string{"model.py": src.String()}, models, m.td.Train, "python3", "model.py")
+}
+
+func TestExportPython(t *testing.T) {
+	testExportPython(t, false)
+}
+
+func TestExportPythonNumpy(t *testing.T) {
+	if exec.Command("python3", "-c", "import numpy").Run() != nil {
+		t.Skip("numpy not found")
+	}
+	testExportPython(t, true)
+}
+
+func TestExportJS(t *testing.T) {
+
+	m, models := randomModels(20)
+
+	var src bytes.Buffer
+	for i, md := range models {
+		s, err := md.Export(&JSEmitter{Name: fmt.Sprintf("model%d", i), Named: i%2 == 1})
+		ok(t, err)
+		src.WriteString(s + "\n")
+	}
+	for i := range models {
+		for _, row := range m.td.Train {
+			if i%2 == 1 {
+				fmt.Fprintf(&src, "console.log(String(model%d(%v, %v)));\n", i, row[0], row[1])
+			} else {
+				fmt.Fprintf(&src, "console.log(String(model%d([%v, %v])));\n", i, row[0], row[1])
+			}
+		}
+	}
+	runExported(t, map[string]string{"model.js": src.String()}, models, m.td.Train, "node", "model.js")
+}
+
+// TestExportSQL - every operator, on values inside its domain since SQL has no NaN or infinity
+func TestExportSQL(t *testing.T) {
+
+	labels := []string{"x0", "x1"}
+	data := [][]float64{{1.5, 2}, {-3, 0.5}, {4, -2.5}, {0.25, 0.75}}
+	var models []*Model
+	for _, expr := range []string{
+		"x0+x1*x0-x1/(2.500000)",
+		"sin(x0)+cos(x1)+tan(x0)",
+		"exp(x1)+log(abs(x0))+sqrt(abs(x1))",
+		"max(x0,x1)-min(x0,x1)",
+		"iif(x0>0,x1,x0)+iif(x0<0,x1,x0)",
+		"iif(x0>x1,x0,x1)-iif(x0<x1,x0,x1)",
+		"iif(x0>x1,1,0)+iif(x0<x1,1,0)",
+		"iif(x0>0 &&x1>0,1,0)+iif(x0>0 ||x1>0,1,0)",
+		"pow(abs(x0),x1)+pow10(x1)",
+		"log10(abs(x0))+log2(abs(x1))",
+		"floor(x0)+ceil(x1)",
+		"inv(x0)+square(x1)",
+		"tanh(x0)+sigmoid(x1)+atan(x0)+atan2(x0,x1)",
+		"fmod(x0,x1)+hypot(x0,x1)+neg(x0)",
+		"cube(x0)+cbrt(x0)+gauss(x1)+sign(x1)+step(x0)",
+	} {
+		md, err := ParseModel(expr, labels)
+		ok(t, err)
+		models = append(models, md)
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "CREATE TABLE t (x0 REAL, x1 REAL);\n")
+	for _, row := range data {
+		fmt.Fprintf(&src, "INSERT INTO t VALUES (%v, %v);\n", row[0], row[1])
+	}
+	for _, md := range models {
+		s, err := md.Export(&SQLEmitter{})
+		ok(t, err)
+		fmt.Fprintf(&src, "SELECT printf('%%.17g', %s) FROM t ORDER BY rowid;\n", s)
+	}
+	runExported(t, map[string]string{"model.sql": src.String()}, models, data, "sh", "-c", "sqlite3 < model.sql")
+}
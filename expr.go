@@ -0,0 +1,179 @@
+package mep
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type nodeKind int
+
+const (
+	variableNode nodeKind = iota
+	constantNode
+	operatorNode
+)
+
+// node - expression tree derived from a program, or built by the expression parser
+type node struct {
+	kind  nodeKind
+	op    int     // operator code for operators, index for variables
+	value float64 // value for constants
+	args  []*node
+}
+
+func (n *node) isOp(ops ...int) bool {
+	if n.kind != operatorNode {
+		return false
+	}
+	for _, op := range ops {
+		if n.op == op {
+			return true
+		}
+	}
+	return false
+}
+
+// buildTree - expand gene poz of a program into an expression tree
+func buildTree(code program, consts constants, numVariables, poz int) *node {
+
+	op := code[poz].op
+
+	if op >= 0 {
+		if op < numVariables {
+			return &node{kind: variableNode, op: op}
+		}
+		return &node{kind: constantNode, value: consts[op-numVariables]}
+	}
+
+	n := &node{kind: operatorNode, op: op}
+	for _, adr := range code[poz].args() {
+		n.args = append(n.args, buildTree(code, consts, numVariables, adr))
+	}
+	return n
+}
+
+// args - addresses of the arguments of an operator gene
+func (gene instruction) args() []int {
+	adr := []int{gene.adr1, gene.adr2, gene.adr3, gene.adr4}
+	return adr[:operatorOf(gene.op).Arity()]
+}
+
+func (m *Mep) tree(individual chromosome, poz int) *node {
+	return buildTree(individual.program, individual.constants, m.numVariables, poz)
+}
+
+// format - append the infix representation of the tree to exp, with constants in full precision
+func (n *node) format(exp string, labels []string) string {
+	return n.formatNumbers(exp, labels, "")
+}
+
+// formatNumbers - append the infix representation of the tree to exp, with constants
+// formatted by numberFormat (see formatNumber). Operands are parenthesised whenever
+// the evaluation order would otherwise change, so that the text parses back into
+// an expression computing the same values.
+func (n *node) formatNumbers(exp string, labels []string, numberFormat string) string {
+
+	switch n.kind {
+	case variableNode:
+		return exp + labels[n.op]
+	case constantNode:
+		return exp + formatNumber(n.value, numberFormat)
+	}
+
+	a := n.args
+	arg := func(exp string, i int) string {
+		return a[i].formatNumbers(exp, labels, numberFormat)
+	}
+	switch n.op {
+	case -1, -2, -3, -4: // + - * /
+		prec := n.prec()
+		// operators are left associative: a-(b-c) and a/(b*c) need parentheses, (a-b)-c does not
+		if a[0].prec() < prec {
+			exp = arg(exp+"(", 0) + ")"
+		} else {
+			exp = arg(exp, 0)
+		}
+		exp += [...]string{"+", "-", "*", "/"}[-n.op-1]
+		if a[1].prec() <= prec {
+			exp = arg(exp+"(", 1) + ")"
+		} else {
+			exp = arg(exp, 1)
+		}
+	case -14: // ifgtz
+		exp = arg(exp+"iif(", 0) + ">0,"
+		exp = arg(exp, 1) + ","
+		exp = arg(exp, 2) + ")"
+	case -15: // ifltz
+		exp = arg(exp+"iif(", 0) + "<0,"
+		exp = arg(exp, 1) + ","
+		exp = arg(exp, 2) + ")"
+	case -16, -17: // ifgt, iflt
+		exp = arg(exp+"iif(", 0)
+		if n.op == -16 {
+			exp += ">"
+		} else {
+			exp += "<"
+		}
+		exp = arg(exp, 1) + ","
+		exp = arg(exp, 2) + ","
+		exp = arg(exp, 3) + ")"
+	case -18, -19: // ifbgt, ifblt
+		exp = arg(exp+"iif(", 0)
+		if n.op == -18 {
+			exp += ">"
+		} else {
+			exp += "<"
+		}
+		exp = arg(exp, 1) + ",1,0)"
+	case -20: // and
+		exp = arg(exp+"iif(", 0) + ">0 &&"
+		exp = arg(exp, 1) + ">0,1,0)"
+	case -21: // or
+		exp = arg(exp+"iif(", 0) + ">0 ||"
+		exp = arg(exp, 1) + ">0,1,0)"
+	default: // function call syntax: name(arg,...)
+		exp += operatorOf(n.op).Name() + "("
+		for i := range a {
+			if i > 0 {
+				exp += ","
+			}
+			exp = arg(exp, i)
+		}
+		exp += ")"
+	}
+	return exp
+}
+
+// prec - binding strength of the infix representation of the node
+func (n *node) prec() int {
+	switch {
+	case n.isOp(-1, -2):
+		return 1
+	case n.isOp(-3, -4):
+		return 2
+	}
+	return 3
+}
+
+// formatNumber - format a constant with a fmt verb such as %g or %.3f, or in full precision
+// (the shortest text parsing back to the same value) when numberFormat is empty.
+// Negative numbers are parenthesised.
+func formatNumber(v float64, numberFormat string) string {
+	var s string
+	switch {
+	case math.IsNaN(v):
+		s = "NaN"
+	case math.IsInf(v, 0):
+		s = strings.TrimPrefix(strconv.FormatFloat(v, 'g', -1, 64), "+")
+	case numberFormat == "":
+		s = strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		s = fmt.Sprintf(numberFormat, v)
+	}
+	if strings.HasPrefix(s, "-") {
+		return "(" + s + ")"
+	}
+	return s
+}
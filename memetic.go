@@ -0,0 +1,83 @@
+package mep
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// SetLocalSearch - every `every` generations, improve the best `best` individuals of each
+// sub-population by hill climbing: `budget` times, a single gene of the output is mutated
+// (a new op code, or a new address of one of the operator's arguments) and the change is
+// kept if it improves the fitness.
+// A zero argument disables the local search (default).
+func (m *Mep) SetLocalSearch(best, every, budget int) {
+	if best < 0 || every < 0 || budget < 0 {
+		panic("invalid local search")
+	}
+	m.localSearchBest = best
+	m.localSearchEvery = every
+	m.localSearchBudget = budget
+}
+
+// LocalSearchImprovement - number of individuals whose fitness was improved by the local search
+// since the population was initialised, and the sum of the improvements
+func (m *Mep) LocalSearchImprovement() (int, float64) {
+	return m.localSearchImproved, m.localSearchGain
+}
+
+// localSearch - hill climbing from the best individuals of each sub-population
+func (m *Mep) localSearch() {
+	for p := range m.pop {
+		best := m.localSearchBest
+		if best > m.subPopSize {
+			best = m.subPopSize
+		}
+		for i := 0; i < best; i++ {
+			m.hillClimb(p, &m.pop[p][i])
+		}
+		sort.Sort(m.pop[p])
+	}
+}
+
+// hillClimb - single gene mutations of the chromosome, keeping those improving its fitness
+func (m *Mep) hillClimb(p int, c *chromosome) {
+
+	if c.bestIndex < 0 || math.IsNaN(c.fitness) || math.IsInf(c.fitness, 0) {
+		return
+	}
+	before := c.fitness
+	trial := c.clone()
+	for step := 0; step < m.localSearchBudget; step++ {
+		var genes []int
+		for i, active := range activeGenes(c.program, c.bestIndex) {
+			if active {
+				genes = append(genes, i)
+			}
+		}
+		m.copyChromosome(c, &trial)
+		m.mutateGene(&trial, genes[rand.Intn(len(genes))])
+		m.eval(m.results[p], &trial)
+		if better(trial.fitness, c.fitness) {
+			m.copyChromosome(&trial, c)
+		}
+	}
+	if c.fitness < before {
+		m.localSearchImproved++
+		m.localSearchGain += before - c.fitness
+	}
+}
+
+// mutateGene - a new op code for gene i, or a new address for one of its arguments
+func (m *Mep) mutateGene(c *chromosome, i int) {
+	gene := &c.program[i]
+	switch {
+	case i == 0:
+		gene.op = m.randomTerminal()
+	case gene.op >= 0 || rand.Intn(2) == 0:
+		gene.op = m.randomCode(i)
+	default:
+		adr := []*int{&gene.adr1, &gene.adr2, &gene.adr3, &gene.adr4}[rand.Intn(operatorOf(gene.op).Arity())]
+		*adr = m.randomAdr(i)
+	}
+}
@@ -0,0 +1,42 @@
+package mep
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestLocalSearch(t *testing.T) {
+
+	m := New(NewPythagorean(20), TotalErrorFF)
+	m.SetPop(20, 2, 20)
+	m.SetLocalSearch(3, 1, 50)
+
+	// the fitness is never worse after hill climbing
+	c := m.pop[0][10].clone()
+	before := c.fitness
+	m.hillClimb(0, &c)
+	if better(before, c.fitness) {
+		t.Errorf("fitness %f after hill climbing from %f", c.fitness, before)
+	}
+	improved, gain := m.LocalSearchImprovement()
+	if improved == 0 {
+		equals(t, before, c.fitness)
+	} else {
+		equals(t, 1, improved)
+		equals(t, before-c.fitness, gain)
+	}
+
+	// the best individuals of random populations are improved at each generation
+	m.randomPopulation()
+	for i := 0; i < 5; i++ {
+		m.Evolve()
+	}
+	if improved, _ = m.LocalSearchImprovement(); improved == 0 {
+		t.Errorf("no individual improved by the local search")
+	}
+	for _, subPop := range m.pop {
+		if !sort.IsSorted(subPop) {
+			t.Errorf("sub-population not sorted")
+		}
+	}
+}
@@ -152,6 +152,11 @@ type chromosome struct {
 	constants constants
 	fitness   float64
 	bestIndex int
+	errors    []float64 // per case, see Candidates.Errors
+	size      int       // active genes of the output when ties are broken by size (see SetSizeTieBreak), else 0
+	error     float64   // fitness of the output without the size penalty (see SetSizePenalty)
+	rank      int       // non-domination rank, see SetPareto
+	crowding  float64   // crowding distance in its front, see SetPareto
 }
 
 type subPopulation []chromosome
@@ -164,20 +169,41 @@ func (slice subPopulation) Len() int {
 }
 
 func (slice subPopulation) Less(i, j int) bool {
-	return slice[i].fitness < slice[j].fitness
+	return fitter(&slice[i], &slice[j])
+}
+
+// better - whether fitness a is better (lower) than b; NaN is the worst fitness,
+// so that it keeps the comparisons of sorting and selection consistent
+func better(a, b float64) bool {
+	return a < b || (math.IsNaN(b) && !math.IsNaN(a))
+}
+
+// finite - whether all values are neither NaN nor infinite
+func finite(values []float64) bool {
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return true
 }
 
 func (slice subPopulation) Swap(i, j int) {
 	slice[i], slice[j] = slice[j], slice[i]
 }
 
-type operator struct {
-	op      int
-	name    string
-	enabled bool
+// sift - move chromosome i to its place in the otherwise sorted sub-population, returning that place
+func (slice subPopulation) sift(i int) int {
+	for ; i > 0 && fitter(&slice[i], &slice[i-1]); i-- {
+		slice.Swap(i, i-1)
+	}
+	for ; i < len(slice)-1 && fitter(&slice[i+1], &slice[i]); i++ {
+		slice.Swap(i, i+1)
+	}
+	return i
 }
 
-// CrossoverType - uniform or onecutpoint
+// CrossoverType - onecutpoint, uniform, twopoint, npoint or subtree
 type CrossoverType int
 
 const (
@@ -185,6 +211,12 @@ const (
 	OneCutPoint CrossoverType = iota
 	// Uniform - crossover type
 	Uniform
+	// TwoPoint - crossover type, exchanging the genes between two cut points
+	TwoPoint
+	// NPoint - crossover type, alternating the parents at each of the cut points (see SetCrossoverPoints)
+	NPoint
+	// Subtree - crossover type, transplanting the genes a random gene of the other parent depends on
+	Subtree
 )
 
 // Mep - primary class
@@ -212,6 +244,38 @@ type Mep struct {
 	results              [][][]float64
 	operators            []operator
 	crossoverType        CrossoverType
+	crossoverPoints      int
+	mutationPipeline     []MutationStep
+	replacement          ReplacementType
+	elitism              int
+	lambda               int
+	generation           int
+	constOptBest         int
+	constOptEvery        int
+	constOptEvaluations  int
+	constOptImproved     int
+	constOptGain         float64
+	constOptimizer       ConstOptimizer
+	localSearchBest      int
+	localSearchEvery     int
+	localSearchBudget    int
+	localSearchImproved  int
+	localSearchGain      float64
+	seeds                []*Model
+	seedFraction         float64
+	numberFormat         string
+	fixedOutput          bool
+	operAdaptation       float64
+	operUsage            []float64
+	domainPolicy         DomainPolicy
+	sizePenalty          float64
+	sizeTieBreak         bool
+	maxSize              int
+	maxDepth             int
+	pareto               bool
+	archive              subPopulation
+	invalidEvals         int
+	selection            Selection
 }
 
 // New - create a new Multi-Expression population
@@ -228,37 +292,8 @@ func New(td TrainingData, ff FitnessFunction) *Mep {
 		panic("Invalid data")
 	}
 
-	m.operators = []operator{
-		{-1, "add", true},
-		{-2, "sub", true},
-		{-3, "mul", true},
-		{-4, "div", true},
-		{-5, "sin", false},
-		{-6, "cos", false},
-		{-7, "tan", false},
-		{-8, "exp", false},
-		{-9, "log", false},
-		{-10, "sqrt", false},
-		{-11, "abs", false},
-		{-12, "max", false},
-		{-13, "min", false},
-		{-14, "ifgtz", false},
-		{-15, "ifltz", false},
-		{-16, "ifgt", false},
-		{-17, "iflt", false},
-		{-18, "ifbgt", false},
-		{-19, "ifblt", false},
-		{-20, "and", false},
-		{-21, "or", false},
-		{-22, "pow", false},
-		{-23, "pow10", false},
-		{-24, "log10", false},
-		{-25, "log2", false},
-		{-26, "floor", false},
-		{-27, "ceil", false},
-		{-28, "inv", false},
-		{-29, "square", false},
-	}
+	m.operators = make([]operator, len(registeredOperators))
+	copy(m.operators, registeredOperators)
 
 	// defaults
 	m.subPopSize = 100
@@ -274,6 +309,9 @@ func New(td TrainingData, ff FitnessFunction) *Mep {
 	m.constantsProbability = 0
 	m.numConstants = 0
 	m.crossoverType = OneCutPoint
+	m.crossoverPoints = 3
+	m.elitism = 1
+	m.selection = TournamentSelection{Size: 2}
 
 	// initialize population
 	m.randomPopulation()
@@ -290,6 +328,7 @@ func (m *Mep) SetPop(popSize, numSubpopulation, codeLength int) {
 	if codeLength < 4 {
 		panic("invalid codeLength, should be >= 4")
 	}
+	checkReplacement(m.replacement, m.elitism, m.lambda, popSize)
 	m.subPopSize = popSize
 	m.numSubpopulation = numSubpopulation
 	m.codeLength = codeLength
@@ -334,7 +373,7 @@ func (m *Mep) SetConst(fixed []float64, numRand int, minRand, maxRand float64) {
 // SetOper - enable/disable operator
 func (m *Mep) SetOper(operName string, state bool) {
 	for index := 0; index < len(m.operators); index++ {
-		if m.operators[index].name == operName {
+		if m.operators[index].Name() == operName && !protectedVersion(m.operators[index].op) {
 			if state && !m.operators[index].enabled {
 				m.operators[index].enabled = true
 			} else if !state && m.operators[index].enabled {
@@ -348,17 +387,53 @@ func (m *Mep) SetOper(operName string, state bool) {
 func (m *Mep) Oper(all bool) []string {
 	var operators []string
 	for index := 0; index < len(m.operators); index++ {
+		if protectedVersion(m.operators[index].op) {
+			continue
+		}
 		if all {
-			operators = append(operators, m.operators[index].name)
+			operators = append(operators, m.operators[index].Name())
 		} else if m.operators[index].enabled {
-			operators = append(operators, m.operators[index].name)
+			operators = append(operators, m.operators[index].Name())
 		}
 	}
 	return operators
 }
 
+// SetSeedModels - seed a fraction (valid range 0.0 - 1.0) of each subpopulation with the given models (resets population)
+func (m *Mep) SetSeedModels(models []*Model, fraction float64) error {
+	if fraction < 0.0 || fraction > 1.0 {
+		panic("invalid seed fraction")
+	}
+	for _, md := range models {
+		if err := m.checkSeed(md); err != nil {
+			return err
+		}
+	}
+	m.seeds = models
+	m.seedFraction = fraction
+	// initialize population
+	m.randomPopulation()
+	return nil
+}
+
+// SetSeedExpr - seed a fraction (valid range 0.0 - 1.0) of each subpopulation with the given expressions (resets population)
+func (m *Mep) SetSeedExpr(exprs []string, fraction float64) error {
+	var models []*Model
+	for _, expr := range exprs {
+		md, err := m.ParseExpr(expr)
+		if err != nil {
+			return err
+		}
+		models = append(models, md)
+	}
+	return m.SetSeedModels(models, fraction)
+}
+
 // SetCrossover - crossover type and probability (valid range 0.0 - 1.0)
 func (m *Mep) SetCrossover(crossoverType CrossoverType, crossoverProbability float64) {
+	if crossoverType < OneCutPoint || crossoverType > Subtree {
+		panic("invalid crossover type")
+	}
 	m.crossoverType = crossoverType
 	m.crossoverProbability = crossoverProbability
 	if m.crossoverProbability < 0.0 || m.crossoverProbability > 1.0 {
@@ -366,6 +441,14 @@ func (m *Mep) SetCrossover(crossoverType CrossoverType, crossoverProbability flo
 	}
 }
 
+// SetCrossoverPoints - number of cut points of the NPoint crossover (default 3)
+func (m *Mep) SetCrossoverPoints(points int) {
+	if points < 1 {
+		panic("invalid crossover points")
+	}
+	m.crossoverPoints = points
+}
+
 // SetMutation - mutation probability (valid range 0.0 - 1.0)
 func (m *Mep) SetMutation(mutationProbability float64) {
 	m.mutationProbability = mutationProbability
@@ -374,6 +457,60 @@ func (m *Mep) SetMutation(mutationProbability float64) {
 	}
 }
 
+// SetOperWeight - relative probability of choosing an enabled operator (default 1, 0 never chooses it)
+func (m *Mep) SetOperWeight(operName string, weight float64) {
+	if weight < 0 || math.IsInf(weight, 0) || math.IsNaN(weight) {
+		panic("invalid operator weight")
+	}
+	for index := 0; index < len(m.operators); index++ {
+		if m.operators[index].Name() == operName && !protectedVersion(m.operators[index].op) {
+			m.operators[index].weight = weight
+		}
+	}
+}
+
+// OperWeights - current weights of the enabled operators
+func (m *Mep) OperWeights() map[string]float64 {
+	weights := map[string]float64{}
+	for _, o := range m.operators {
+		if o.enabled {
+			weights[o.Name()] = o.weight
+		}
+	}
+	return weights
+}
+
+// SetOperAdaptation - adapt the operator weights after each generation, moving them by rate
+// (valid range 0.0 - 1.0, 0 disables adaptation) towards the share of each operator among
+// the active genes of offspring better than both of their parents
+func (m *Mep) SetOperAdaptation(rate float64) {
+	if rate < 0.0 || rate > 1.0 {
+		panic("invalid operator adaptation rate")
+	}
+	m.operAdaptation = rate
+	m.operUsage = make([]float64, len(m.operators))
+}
+
+// SetFixedOutput - use the last gene of each chromosome as its output instead of the best one.
+// Only the genes it depends on are evaluated, which is faster with large training data.
+// The population is reinitialised.
+func (m *Mep) SetFixedOutput(fixed bool) {
+	m.fixedOutput = fixed
+	// initialize population
+	m.randomPopulation()
+}
+
+// SetNumberFormat - fmt verb for the constants of printed expressions, such as %g or %.3f.
+// The default ("") prints constants in full precision, so that expressions parse back exactly.
+func (m *Mep) SetNumberFormat(numberFormat string) {
+	if numberFormat != "" {
+		if _, err := strconv.ParseFloat(fmt.Sprintf(numberFormat, 1.5), 64); err != nil {
+			panic("invalid numberFormat")
+		}
+	}
+	m.numberFormat = numberFormat
+}
+
 // SetProb - set mutation/crossover probability (valid range 0.0 - 1.0)
 func (m *Mep) SetProb(mutationProbability, crossoverProbability float64) {
 	m.mutationProbability = mutationProbability
@@ -393,44 +530,105 @@ func (m *Mep) Evolve() {
 		panic("probabilities must sum to 1.0")
 	}
 
+	m.invalidEvals = 0
+	replacement := m.replacement
+	if m.pareto {
+		replacement = MuPlusLambda // the survivors are chosen by rank
+	}
 	for p := 0; p < m.numSubpopulation; p++ {
 
+		if m.pareto {
+			m.rankPareto(m.pop[p])
+		}
+
 		offspring1 := m.randomChromosome(p)
 		offspring2 := m.randomChromosome(p)
+		numOffspring := m.numOffspring()
+		var offspring subPopulation
 
-		for k := 0; k < m.subPopSize; k += 2 {
+		for k := 0; k < numOffspring; k += 2 {
 
-			// binary tournament
-			r1 := m.tournamentSelection(p, 2)
-			r2 := m.tournamentSelection(p, 2)
+			// selection
+			r1 := m.selectParent(p)
+			r2 := m.selectParent(p)
 			m.copyChromosome(&m.pop[p][r1], &offspring1)
 			m.copyChromosome(&m.pop[p][r2], &offspring2)
 			// crossover
 			if rand.Float64() < m.crossoverProbability {
-				if m.crossoverType == OneCutPoint {
+				switch m.crossoverType {
+				case OneCutPoint:
 					m.oneCutPointCrossover(&m.pop[p][r1], &m.pop[p][r2], &offspring1, &offspring2)
-				} else if m.crossoverType == Uniform {
+				case Uniform:
 					m.uniformCrossover(&m.pop[p][r1], &m.pop[p][r2], &offspring1, &offspring2)
-				} else {
+				case TwoPoint:
+					m.nPointCrossover(2, &m.pop[p][r1], &m.pop[p][r2], &offspring1, &offspring2)
+				case NPoint:
+					m.nPointCrossover(m.crossoverPoints, &m.pop[p][r1], &m.pop[p][r2], &offspring1, &offspring2)
+				case Subtree:
+					m.subtreeCrossover(&m.pop[p][r1], &m.pop[p][r2], &offspring1, &offspring2)
+				default:
 					panic("invalid crossover type")
 				}
 			}
 
 			// mutatation
 			m.mutation(&offspring1)
-			m.eval(m.results[p], &offspring1)
+			if !m.eval(m.results[p], &offspring1) {
+				m.invalidEvals++
+			}
 
 			m.mutation(&offspring2)
-			m.eval(m.results[p], &offspring2)
+			if !m.eval(m.results[p], &offspring2) {
+				m.invalidEvals++
+			}
 
-			// replace the worst in the population
-			if offspring1.fitness < m.pop[p][m.subPopSize-1].fitness {
-				m.copyChromosome(&offspring1, &m.pop[p][m.subPopSize-1])
+			if m.operAdaptation > 0 {
+				parentFitness := m.pop[p][r1].fitness
+				if better(m.pop[p][r2].fitness, parentFitness) {
+					parentFitness = m.pop[p][r2].fitness
+				}
+				m.countOperators(&offspring1, parentFitness)
+				m.countOperators(&offspring2, parentFitness)
 			}
-			if offspring2.fitness < m.pop[p][m.subPopSize-1].fitness {
-				m.copyChromosome(&offspring2, &m.pop[p][m.subPopSize-1])
+
+			switch replacement {
+			case SteadyState:
+				// replace the worst in the population, keeping it sorted
+				if fitter(&offspring1, &m.pop[p][m.subPopSize-1]) {
+					m.copyChromosome(&offspring1, &m.pop[p][m.subPopSize-1])
+					m.pop[p].sift(m.subPopSize - 1)
+				}
+				if fitter(&offspring2, &m.pop[p][m.subPopSize-1]) {
+					m.copyChromosome(&offspring2, &m.pop[p][m.subPopSize-1])
+					m.pop[p].sift(m.subPopSize - 1)
+				}
+			case ReplaceParent:
+				if fitter(&offspring1, &m.pop[p][r1]) {
+					m.copyChromosome(&offspring1, &m.pop[p][r1])
+					moved := m.pop[p].sift(r1)
+					// the second parent may have moved along
+					switch {
+					case r2 == r1:
+						r2 = moved
+					case r1 < r2 && r2 <= moved:
+						r2--
+					case moved <= r2 && r2 < r1:
+						r2++
+					}
+				}
+				if fitter(&offspring2, &m.pop[p][r2]) {
+					m.copyChromosome(&offspring2, &m.pop[p][r2])
+					m.pop[p].sift(r2)
+				}
+			default:
+				// the offspring replace the population at the end of the generation
+				offspring = append(offspring, offspring1.clone())
+				if len(offspring) < numOffspring {
+					offspring = append(offspring, offspring2.clone())
+				}
 			}
 		}
+		m.replace(p, offspring)
 
 		// now copy one individual from one population to the next one.
 		// the copied invidual will replace the worst in the next one (if is better)
@@ -439,17 +637,36 @@ func (m *Mep) Evolve() {
 		// replace the worst in the next population (p + 1) - only if is better
 		indexNextPop := (p + 1) % m.numSubpopulation // index of the next subpopulation (taken in circular order)
 
-		if m.pop[p][k].fitness < m.pop[indexNextPop][m.subPopSize-1].fitness {
+		if fitter(&m.pop[p][k], &m.pop[indexNextPop][m.subPopSize-1]) {
 			m.copyChromosome(&m.pop[p][k], &m.pop[indexNextPop][m.subPopSize-1])
 			sort.Sort(m.pop[indexNextPop])
 		}
 
 		sort.Sort(m.pop[p])
+	}
 
-		if m.pop[p][0].fitness < m.pop[m.bestPop][0].fitness {
+	m.generation++
+	if m.constOptBest > 0 && m.constOptEvery > 0 && m.constOptEvaluations > 0 && m.generation%m.constOptEvery == 0 {
+		m.optimizeConstants()
+	}
+	if m.localSearchBest > 0 && m.localSearchEvery > 0 && m.localSearchBudget > 0 && m.generation%m.localSearchEvery == 0 {
+		m.localSearch()
+	}
+
+	// the best individual may be lost by the replacement
+	m.bestPop = 0
+	for p := 1; p < m.numSubpopulation; p++ {
+		if fitter(&m.pop[p][0], &m.pop[m.bestPop][0]) {
 			m.bestPop = p
 		}
 	}
+
+	if m.operAdaptation > 0 {
+		m.adaptOperWeights()
+	}
+	if m.pareto {
+		m.updateArchive()
+	}
 }
 
 // Solve - Evolve until fitnessThreshold or numGens is reached. Returns generations and total time
@@ -470,9 +687,15 @@ func (m *Mep) Solve(numGens int, fitnessThreshold float64, showProgress bool) (i
 	return gens, time.Since(start)
 }
 
-// BestFitness - return the best fitness of the population
+// BestFitness - return the best fitness of the population, without the size penalty (see SetSizePenalty)
 func (m *Mep) BestFitness() float64 {
-	return m.pop[m.bestPop][0].fitness
+	return m.pop[m.bestPop][0].error
+}
+
+// InvalidEvals - number of offspring of the last generation without a finite output
+// and fitness (see SetDomainPolicy); they are never chosen over valid individuals
+func (m *Mep) InvalidEvals() int {
+	return m.invalidEvals
 }
 
 // BestExpr - return the best expression of the population
@@ -482,13 +705,14 @@ func (m *Mep) BestExpr() string {
 
 // Best - return the best fitness,expression of the population
 func (m *Mep) Best() (float64, string) {
-	return m.pop[m.bestPop][0].fitness, m.parse("", m.pop[m.bestPop][0], m.pop[m.bestPop][0].bestIndex)
+	return m.pop[m.bestPop][0].error, m.parse("", m.pop[m.bestPop][0], m.pop[m.bestPop][0].bestIndex)
 }
 
 // PrintBest - print the best member of the population
 func (m *Mep) PrintBest() {
 	exp := m.parse("", m.pop[m.bestPop][0], m.pop[m.bestPop][0].bestIndex)
-	fmt.Printf("expr='%s' # fitness = %f\n", exp, m.pop[m.bestPop][0].fitness)
+	size, _ := m.BestSize()
+	fmt.Printf("expr='%s' # fitness = %f, size = %d\n", exp, m.pop[m.bestPop][0].error, size)
 }
 
 // PrintTestData - print the testdata
@@ -500,187 +724,60 @@ func (m *Mep) PrintTestData() {
 	}
 }
 
-func (m *Mep) eval(results [][]float64, c *chromosome) {
+// eval - evaluate the chromosome, choosing its output; false if it has no finite fitness
+func (m *Mep) eval(results [][]float64, c *chromosome) bool {
 
-	c.fitness = 1e+308
+	c.fitness = math.NaN()
+	c.error = math.NaN()
 	c.bestIndex = -1
+	c.errors = nil
+
+	// with a fixed output only the genes it depends on are needed; a gene mutated
+	// into a terminal below may leave some of them unused, which is harmless
+	var active []bool
+	if m.fixedOutput {
+		c.bestIndex = m.codeLength - 1
+		active = activeGenes(c.program, c.bestIndex)
+	}
+	var invalid []bool
+	if m.domainPolicy == Penalize {
+		invalid = make([]bool, m.codeLength)
+	}
+	sized := m.sizePenalty > 0 || m.sizeTieBreak || m.maxSize > 0
+	var sets activeSets
+	if sized {
+		sets = newActiveSets(m.codeLength)
+	}
+	var depths []int
+	if m.maxDepth > 0 {
+		depths = make([]int, m.codeLength)
+	}
+	c.size = 0
 
 	// we keep intermediate values in a matrix because when an error occurs (like division by 0) we mutate that gene into a variables.
 	// in such case it is faster to have all intermediate results until current gene, so that we don't have to recompute them again.
 
 	for i := 0; i < m.codeLength; i++ { // read the chromosome from top to down
 
-		isErrorCase := false
-		switch c.program[i].op {
-		case -1: // +
-			for k := 0; k < m.numTraining; k++ {
-				results[i][k] = results[c.program[i].adr1][k] + results[c.program[i].adr2][k]
-			}
-		case -2: // -
-			for k := 0; k < m.numTraining; k++ {
-				results[i][k] = results[c.program[i].adr1][k] - results[c.program[i].adr2][k]
-			}
-		case -3: // *
-			for k := 0; k < m.numTraining; k++ {
-				results[i][k] = results[c.program[i].adr1][k] * results[c.program[i].adr2][k]
-			}
-		case -4: //  /
-			for k := 0; k < m.numTraining; k++ {
-				if math.Abs(results[c.program[i].adr2][k]) < 1e-6 { // a small constant
-					isErrorCase = true
-				}
-			}
-			if isErrorCase { // an division by zero error occured !!!
-				c.program[i].op = rand.Intn(m.numVariables) // the gene is mutated into a terminal
-				for k := 0; k < m.numTraining; k++ {
-					results[i][k] = m.td.Train[k][c.program[i].op]
-				}
-			} else { // normal execution....
-				for k := 0; k < m.numTraining; k++ {
-					results[i][k] = results[c.program[i].adr1][k] / results[c.program[i].adr2][k]
-				}
-			}
-		case -5: //  sin
-			for k := 0; k < m.numTraining; k++ {
-				results[i][k] = math.Sin(results[c.program[i].adr1][k])
-			}
-		case -6: //  cos
-			for k := 0; k < m.numTraining; k++ {
-				results[i][k] = math.Cos(results[c.program[i].adr1][k])
-			}
-		case -7: //  tan
-			for k := 0; k < m.numTraining; k++ {
-				results[i][k] = math.Tan(results[c.program[i].adr1][k])
-			}
-		case -8: //  exp
-			for k := 0; k < m.numTraining; k++ {
-				results[i][k] = math.Exp(results[c.program[i].adr1][k])
-			}
-		case -9: //  log
-			for k := 0; k < m.numTraining; k++ {
-				results[i][k] = math.Log(results[c.program[i].adr1][k])
-			}
-		case -10: //  sqrt
-			for k := 0; k < m.numTraining; k++ {
-				results[i][k] = math.Sqrt(results[c.program[i].adr1][k])
-			}
-		case -11: //  abs
-			for k := 0; k < m.numTraining; k++ {
-				results[i][k] = math.Abs(results[c.program[i].adr1][k])
-			}
-		case -12: // max
-			for k := 0; k < m.numTraining; k++ {
-				if results[c.program[i].adr1][k] > results[c.program[i].adr2][k] {
-					results[i][k] = results[c.program[i].adr1][k]
-				} else {
-					results[i][k] = results[c.program[i].adr2][k]
-				}
-			}
-		case -13: // min
-			for k := 0; k < m.numTraining; k++ {
-				if results[c.program[i].adr1][k] < results[c.program[i].adr2][k] {
-					results[i][k] = results[c.program[i].adr1][k]
-				} else {
-					results[i][k] = results[c.program[i].adr2][k]
-				}
-			}
-		case -14: // ifgtz
-			for k := 0; k < m.numTraining; k++ {
-				if results[c.program[i].adr1][k] > 0.0 {
-					results[i][k] = results[c.program[i].adr2][k]
-				} else {
-					results[i][k] = results[c.program[i].adr3][k]
-				}
-			}
-		case -15: // ifltz
-			for k := 0; k < m.numTraining; k++ {
-				if results[c.program[i].adr1][k] < 0.0 {
-					results[i][k] = results[c.program[i].adr2][k]
-				} else {
-					results[i][k] = results[c.program[i].adr3][k]
-				}
-			}
-		case -16: // ifgt
-			for k := 0; k < m.numTraining; k++ {
-				if results[c.program[i].adr1][k] > results[c.program[i].adr2][k] {
-					results[i][k] = results[c.program[i].adr3][k]
-				} else {
-					results[i][k] = results[c.program[i].adr4][k]
-				}
-			}
-		case -17: // iflt
-			for k := 0; k < m.numTraining; k++ {
-				if results[c.program[i].adr1][k] < results[c.program[i].adr2][k] {
-					results[i][k] = results[c.program[i].adr3][k]
-				} else {
-					results[i][k] = results[c.program[i].adr4][k]
-				}
-			}
-		case -18: // ifbgt
-			for k := 0; k < m.numTraining; k++ {
-				if results[c.program[i].adr1][k] > results[c.program[i].adr2][k] {
-					results[i][k] = 1.0
-				} else {
-					results[i][k] = 0.0
-				}
-			}
-		case -19: // ifblt
-			for k := 0; k < m.numTraining; k++ {
-				if results[c.program[i].adr1][k] < results[c.program[i].adr2][k] {
-					results[i][k] = 1.0
-				} else {
-					results[i][k] = 0.0
-				}
-			}
-		case -20: // and
-			for k := 0; k < m.numTraining; k++ {
-				if results[c.program[i].adr1][k] > 0.0 && results[c.program[i].adr2][k] > 0.0 {
-					results[i][k] = 1.0
+		if active != nil && !active[i] {
+			continue
+		}
+
+		if c.program[i].op < 0 { // an operator
+			if !m.apply(c.program[i], results, results[i]) { // an error occured (like division by 0)
+				if m.domainPolicy == Penalize {
+					invalid[i] = true
 				} else {
-					results[i][k] = 0.0
+					c.program[i].op = rand.Intn(m.numVariables) // the gene is mutated into a terminal
 				}
 			}
-		case -21: // or
-			for k := 0; k < m.numTraining; k++ {
-				if results[c.program[i].adr1][k] > 0.0 || results[c.program[i].adr2][k] > 0.0 {
-					results[i][k] = 1.0
-				} else {
-					results[i][k] = 0.0
+			if m.domainPolicy == Penalize {
+				for _, adr := range c.program[i].args() {
+					invalid[i] = invalid[i] || invalid[adr]
 				}
 			}
-		case -22: // pow
-			for k := 0; k < m.numTraining; k++ {
-				results[i][k] = math.Pow(results[c.program[i].adr1][k], results[c.program[i].adr2][k])
-			}
-		case -23: // pow10
-			for k := 0; k < m.numTraining; k++ {
-				results[i][k] = math.Pow10(int(results[c.program[i].adr1][k]))
-			}
-		case -24: // log10
-			for k := 0; k < m.numTraining; k++ {
-				results[i][k] = math.Log10(results[c.program[i].adr1][k])
-			}
-		case -25: // log2
-			for k := 0; k < m.numTraining; k++ {
-				results[i][k] = math.Log2(results[c.program[i].adr1][k])
-			}
-		case -26: // floor
-			for k := 0; k < m.numTraining; k++ {
-				results[i][k] = math.Floor(results[c.program[i].adr1][k])
-			}
-		case -27: // ceil
-			for k := 0; k < m.numTraining; k++ {
-				results[i][k] = math.Ceil(results[c.program[i].adr1][k])
-			}
-		case -28: // inv
-			for k := 0; k < m.numTraining; k++ {
-				results[i][k] = 1.0 / results[c.program[i].adr1][k]
-			}
-		case -29: // square
-			for k := 0; k < m.numTraining; k++ {
-				results[i][k] = results[c.program[i].adr1][k] * results[c.program[i].adr1][k]
-			}
-		default: // a variable
+		}
+		if c.program[i].op >= 0 { // a variable or constant
 			for k := 0; k < m.numTraining; k++ {
 				if c.program[i].op < m.numVariables {
 					results[i][k] = m.td.Train[k][c.program[i].op]
@@ -690,228 +787,55 @@ func (m *Mep) eval(results [][]float64, c *chromosome) {
 			}
 		}
 
-		fitness := m.ff(results[i], m.td.Target)
-		if c.fitness > fitness {
+		size := 0
+		if sized {
+			size = sets.add(i, c.program[i])
+		}
+		if depths != nil {
+			depths[i] = geneDepth(c.program[i], depths)
+		}
+
+		if m.fixedOutput && i != c.bestIndex {
+			continue
+		}
+		// genes with a domain error, non-finite outputs or beyond the size limits
+		// are only chosen when nothing else is valid
+		outputError := math.Inf(1)
+		if (invalid == nil || !invalid[i]) && finite(results[i]) &&
+			(m.maxSize == 0 || size <= m.maxSize) && (depths == nil || depths[i] <= m.maxDepth) {
+			outputError = m.ff(results[i], m.td.Target)
+		}
+		fitness := outputError + m.sizePenalty*float64(size)
+		if m.fixedOutput || c.bestIndex < 0 || better(fitness, c.fitness) ||
+			m.sizeTieBreak && fitness == c.fitness && size < c.size {
 			c.fitness = fitness
+			c.error = outputError
 			c.bestIndex = i
+			if m.sizeTieBreak {
+				c.size = size
+			}
 		}
 	}
+	return finite([]float64{c.fitness})
 }
 
-func (m *Mep) parse(exp string, individual chromosome, poz int) string {
-
-	code := individual.program
-	op := code[poz].op
-	adr1 := code[poz].adr1
-	adr2 := code[poz].adr2
-	adr3 := code[poz].adr3
-	adr4 := code[poz].adr4
-
-	if op == -1 { // +
-		exp = m.parse(exp, individual, adr1)
-		exp += "+"
-		exp = m.parse(exp, individual, adr2)
-	} else if op == -2 { // -
-		exp = m.parse(exp, individual, adr1)
-		exp += "-"
-		if code[adr2].op == -1 || code[adr2].op == -2 {
-			exp += "("
-		}
-		exp = m.parse(exp, individual, adr2)
-		if code[adr2].op == -1 || code[adr2].op == -2 {
-			exp += ")"
-		}
-	} else if op == -3 { // *
-		if code[adr1].op == -1 || code[adr1].op == -2 {
-			exp += "("
-		}
-		exp = m.parse(exp, individual, adr1)
-		if code[adr1].op == -1 || code[adr1].op == -2 {
-			exp += ")"
-		}
-		exp += "*"
-		if code[adr2].op == -1 || code[adr2].op == -2 {
-			exp += "("
-		}
-		exp = m.parse(exp, individual, adr2)
-		if code[adr2].op == -1 || code[adr2].op == -2 {
-			exp += ")"
-		}
-	} else if op == -4 { // /
-		if code[adr1].op == -1 || code[adr1].op == -2 {
-			exp += "("
-		}
-		exp = m.parse(exp, individual, adr1)
-		if code[adr1].op == -1 || code[adr1].op == -2 {
-			exp += ")"
-		}
-		exp += "/"
-		if code[adr2].op == -1 || code[adr2].op == -2 {
-			exp += "("
-		}
-		exp = m.parse(exp, individual, adr2)
-		if code[adr2].op == -1 || code[adr2].op == -2 {
-			exp += ")"
-		}
-	} else if op == -5 { // sin
-		exp += "sin("
-		exp = m.parse(exp, individual, adr1)
-		exp += ")"
-	} else if op == -6 { // cos
-		exp += "cos("
-		exp = m.parse(exp, individual, adr1)
-		exp += ")"
-	} else if op == -7 { // tan
-		exp += "tan("
-		exp = m.parse(exp, individual, adr1)
-		exp += ")"
-	} else if op == -8 { // exp
-		exp += "exp("
-		exp = m.parse(exp, individual, adr1)
-		exp += ")"
-	} else if op == -9 { // log
-		exp += "log("
-		exp = m.parse(exp, individual, adr1)
-		exp += ")"
-	} else if op == -10 { // sqrt
-		exp += "sqrt("
-		exp = m.parse(exp, individual, adr1)
-		exp += ")"
-	} else if op == -11 { // abs
-		exp += "abs("
-		exp = m.parse(exp, individual, adr1)
-		exp += ")"
-	} else if op == -12 { // max
-		exp += "max("
-		exp = m.parse(exp, individual, adr1)
-		exp += ","
-		exp = m.parse(exp, individual, adr2)
-		exp += ")"
-
-	} else if op == -13 { // min
-		exp += "min("
-		exp = m.parse(exp, individual, adr1)
-		exp += ","
-		exp = m.parse(exp, individual, adr2)
-		exp += ")"
-
-	} else if op == -14 { // ifgtz
-		exp += "iif("
-		exp = m.parse(exp, individual, adr1)
-		exp += ">0,"
-		exp = m.parse(exp, individual, adr2)
-		exp += ","
-		exp = m.parse(exp, individual, adr3)
-		exp += ")"
-
-	} else if op == -15 { // ifltz
-		exp += "iif("
-		exp = m.parse(exp, individual, adr1)
-		exp += "<0,"
-		exp = m.parse(exp, individual, adr2)
-		exp += ","
-		exp = m.parse(exp, individual, adr3)
-		exp += ")"
-
-	} else if op == -16 { // ifgt
-		exp += "iif("
-		exp = m.parse(exp, individual, adr1)
-		exp += ">"
-		exp = m.parse(exp, individual, adr2)
-		exp += ","
-		exp = m.parse(exp, individual, adr3)
-		exp += ","
-		exp = m.parse(exp, individual, adr4)
-		exp += ")"
-
-	} else if op == -17 { // iflt
-		exp += "iif("
-		exp = m.parse(exp, individual, adr1)
-		exp += "<"
-		exp = m.parse(exp, individual, adr2)
-		exp += ","
-		exp = m.parse(exp, individual, adr3)
-		exp += ","
-		exp = m.parse(exp, individual, adr4)
-		exp += ")"
-
-	} else if op == -18 { // ifbgt
-		exp += "iif("
-		exp = m.parse(exp, individual, adr1)
-		exp += ">"
-		exp = m.parse(exp, individual, adr2)
-		exp += ",1,0)"
-
-	} else if op == -19 { // ifblt
-		exp += "iif("
-		exp = m.parse(exp, individual, adr1)
-		exp += "<"
-		exp = m.parse(exp, individual, adr2)
-		exp += ",1,0)"
-
-	} else if op == -20 { // and
-		exp += "iif("
-		exp = m.parse(exp, individual, adr1)
-		exp += ">0 &&"
-		exp = m.parse(exp, individual, adr2)
-		exp += ">0,1,0)"
-
-	} else if op == -21 { // or
-		exp += "iif("
-		exp = m.parse(exp, individual, adr1)
-		exp += ">0 ||"
-		exp = m.parse(exp, individual, adr2)
-		exp += ">0,1,0)"
-
-	} else if op == -22 { // pow
-		exp += "pow("
-		exp = m.parse(exp, individual, adr1)
-		exp += ","
-		exp = m.parse(exp, individual, adr2)
-		exp += ")"
-
-	} else if op == -23 { // pow10
-		exp += "pow10("
-		exp = m.parse(exp, individual, adr1)
-		exp += ")"
-
-	} else if op == -24 { // log10
-		exp += "log10("
-		exp = m.parse(exp, individual, adr1)
-		exp += ")"
-
-	} else if op == -25 { // log2
-		exp += "log2("
-		exp = m.parse(exp, individual, adr1)
-		exp += ")"
-
-	} else if op == -26 { // floor
-		exp += "floor("
-		exp = m.parse(exp, individual, adr1)
-		exp += ")"
-
-	} else if op == -27 { // ceil
-		exp += "ceil("
-		exp = m.parse(exp, individual, adr1)
-		exp += ")"
-
-	} else if op == -28 { // inv
-		exp += "inv("
-		exp = m.parse(exp, individual, adr1)
-		exp += ")"
-
-	} else if op == -29 { // square
-		exp += "square("
-		exp = m.parse(exp, individual, adr1)
-		exp += ")"
-
-	} else if op < m.numVariables {
-		exp += m.td.Labels[op]
+// execute - evaluate an operator gene over every row of the previously computed genes
+func execute(gene instruction, results [][]float64, out []float64) {
+	operatorOf(gene.op).Eval(arguments(gene, results), out)
+}
 
-	} else {
-		exp += fmt.Sprintf("(%f)", individual.constants[op-m.numVariables])
+// arguments - the results an operator gene is applied to
+func arguments(gene instruction, results [][]float64) [][]float64 {
+	args := gene.args()
+	values := make([][]float64, len(args))
+	for i, adr := range args {
+		values[i] = results[adr]
 	}
-	return exp
+	return values
+}
+
+func (m *Mep) parse(exp string, individual chromosome, poz int) string {
+	return m.tree(individual, poz).formatNumbers(exp, m.td.Labels, m.numberFormat)
 }
 
 func (m *Mep) randomTerminal() int {
@@ -929,16 +853,77 @@ func (m *Mep) randomAdr(index int) int {
 	return rand.Intn(index)
 }
 
+// randomOperator - an enabled operator, chosen in proportion to the weights
+func (m *Mep) randomOperator() int {
+	total := 0.0
+	for _, o := range m.operators {
+		if o.enabled {
+			total += o.weight
+		}
+	}
+	if total == 0 {
+		panic("no enabled operator with a positive weight")
+	}
+	r := rand.Float64() * total
+	last := 0
+	for _, o := range m.operators {
+		if o.enabled && o.weight > 0 {
+			r -= o.weight
+			if r < 0 {
+				return o.op
+			}
+			last = o.op
+		}
+	}
+	return last // rounding
+}
+
+// countOperators - record the operators of an offspring that improved on its parents
+func (m *Mep) countOperators(c *chromosome, parentFitness float64) {
+	if !better(c.fitness, parentFitness) || c.bestIndex < 0 {
+		return
+	}
+	for i, active := range activeGenes(c.program, c.bestIndex) {
+		if active && c.program[i].op < 0 {
+			m.operUsage[-c.program[i].op-1]++
+		}
+	}
+}
+
+// adaptOperWeights - move the weights towards the recorded usage, keeping their sum.
+// A tenth of the target is shared equally, so that unused operators are not lost.
+func (m *Mep) adaptOperWeights() {
+	totalUsage, totalWeight, count := 0.0, 0.0, 0
+	for i, o := range m.operators {
+		if o.enabled && o.weight > 0 {
+			totalUsage += m.operUsage[i]
+			totalWeight += o.weight
+			count++
+		}
+	}
+	if totalUsage > 0 {
+		for i := range m.operators {
+			o := &m.operators[i]
+			if o.enabled && o.weight > 0 {
+				target := totalWeight * (0.9*m.operUsage[i]/totalUsage + 0.1/float64(count))
+				o.weight = (1-m.operAdaptation)*o.weight + m.operAdaptation*target
+			}
+		}
+	}
+	for i := range m.operUsage {
+		m.operUsage[i] = 0
+	}
+}
+
 func (m *Mep) randomCode(index int) int {
 	var op int
 	p := rand.Float64()
 	if p <= m.operatorsProbability {
 
-		n := rand.Intn(len(m.operators))
-		for !m.operators[n].enabled {
-			n = rand.Intn(len(m.operators))
+		op = m.randomOperator() // an operator
+		if protected, ok := protectedOperators[op]; ok && m.domainPolicy == Protect {
+			op = protected
 		}
-		op = m.operators[n].op // an operator
 
 	} else {
 
@@ -991,8 +976,49 @@ func (m *Mep) randomChromosome(subPop int) chromosome {
 	return a
 }
 
+func (m *Mep) checkSeed(md *Model) error {
+	if len(md.Labels) != m.numVariables {
+		return fmt.Errorf("seed %q has %d variables, training data has %d", md, len(md.Labels), m.numVariables)
+	}
+	if len(md.program) > m.codeLength {
+		return fmt.Errorf("seed %q needs %d genes, code length is %d", md, len(md.program), m.codeLength)
+	}
+	if len(md.constants) > m.numConstants {
+		return fmt.Errorf("seed %q needs %d constants, only %d configured (see SetConst)", md, len(md.constants), m.numConstants)
+	}
+	return nil
+}
+
+// seedChromosome - a random chromosome whose first genes and constants are taken from the model.
+// With a fixed output, the genes of the pruned model are placed at the end instead.
+func (m *Mep) seedChromosome(subPop int, md *Model) chromosome {
+	a := m.randomChromosome(subPop)
+	if m.fixedOutput {
+		md = md.Prune()
+		offset := m.codeLength - len(md.program)
+		for i, gene := range md.program {
+			if gene.op < 0 {
+				gene.adr1 += offset
+				gene.adr2 += offset
+				gene.adr3 += offset
+				gene.adr4 += offset
+			}
+			a.program[offset+i] = gene
+		}
+	} else {
+		copy(a.program, md.program)
+	}
+	copy(a.constants, md.constants)
+	m.eval(m.results[subPop], &a)
+	return a
+}
+
 func (m *Mep) randomPopulation() {
 
+	m.generation = 0
+	m.constOptImproved, m.constOptGain = 0, 0
+	m.localSearchImproved, m.localSearchGain = 0, 0
+	m.archive = nil
 	// allocate results matrix
 
 	m.results = make([][][]float64, m.numSubpopulation)
@@ -1011,6 +1037,19 @@ func (m *Mep) randomPopulation() {
 		for i := 0; i < m.subPopSize; i++ {
 			m.pop[p][i] = m.randomChromosome(p)
 		}
+		// replace some of them with the seeds, skipping those that no longer fit
+		var seeds []*Model
+		for _, md := range m.seeds {
+			if m.checkSeed(md) == nil {
+				seeds = append(seeds, md)
+			}
+		}
+		if len(seeds) > 0 {
+			numSeeded := int(math.Ceil(m.seedFraction * float64(m.subPopSize)))
+			for i := 0; i < numSeeded; i++ {
+				m.pop[p][i] = m.seedChromosome(p, seeds[i%len(seeds)])
+			}
+		}
 		// sort by fitness ascending
 		sort.Sort(m.pop[p])
 	}
@@ -1018,7 +1057,7 @@ func (m *Mep) randomPopulation() {
 	// find the best individual
 	m.bestPop = 0 // the index of the subpopulation containing the best invidual
 	for p := 1; p < m.numSubpopulation; p++ {
-		if m.pop[p][0].fitness < m.pop[m.bestPop][0].fitness {
+		if fitter(&m.pop[p][0], &m.pop[m.bestPop][0]) {
 			m.bestPop = p
 		}
 	}
@@ -1045,8 +1084,88 @@ func (m *Mep) oneCutPointCrossover(parent1, parent2, offspring1, offspring2 *chr
 			offspring2.constants[i] = parent2.constants[i]
 		}
 		for i := cuttingPoint; i < m.numConstants; i++ {
-			offspring1.constants[i] = parent1.constants[i]
-			offspring2.constants[i] = parent2.constants[i]
+			offspring1.constants[i] = parent2.constants[i]
+			offspring2.constants[i] = parent1.constants[i]
+		}
+	}
+}
+
+// nPointCrossover - the offspring take the genes, and the constants, alternately from
+// each parent between n random cut points
+func (m *Mep) nPointCrossover(n int, parent1, parent2, offspring1, offspring2 *chromosome) {
+
+	crossed := cutPoints(m.codeLength, n)
+	for i := 0; i < m.codeLength; i++ {
+		if crossed[i] {
+			offspring1.program[i] = parent2.program[i]
+			offspring2.program[i] = parent1.program[i]
+		} else {
+			offspring1.program[i] = parent1.program[i]
+			offspring2.program[i] = parent2.program[i]
+		}
+	}
+
+	// now the constants
+	if m.numConstants > 0 {
+		crossed = cutPoints(m.numConstants, n)
+		for i := 0; i < m.numConstants; i++ {
+			if crossed[i] {
+				offspring1.constants[i] = parent2.constants[i]
+				offspring2.constants[i] = parent1.constants[i]
+			} else {
+				offspring1.constants[i] = parent1.constants[i]
+				offspring2.constants[i] = parent2.constants[i]
+			}
+		}
+	}
+}
+
+// cutPoints - for each position, whether it comes after an odd number of n random cut points
+func cutPoints(length, n int) []bool {
+	cuts := make([]int, length+1)
+	for i := 0; i < n; i++ {
+		cuts[rand.Intn(length)]++
+	}
+	crossed := make([]bool, length)
+	odd := false
+	for i := range crossed {
+		odd = odd != (cuts[i]%2 == 1)
+		crossed[i] = odd
+	}
+	return crossed
+}
+
+// subtreeCrossover - each offspring is a copy of a parent where the genes a random active gene
+// depends on are replaced by those of the other parent (at the same positions, so that their
+// addresses stay valid), along with the constants they use: the genes using that gene now use
+// the other parent's sub-expression
+func (m *Mep) subtreeCrossover(parent1, parent2, offspring1, offspring2 *chromosome) {
+	m.transplant(parent1, parent2, offspring1)
+	m.transplant(parent2, parent1, offspring2)
+}
+
+func (m *Mep) transplant(receiver, donor, offspring *chromosome) {
+
+	copy(offspring.program, receiver.program)
+	copy(offspring.constants, receiver.constants)
+
+	// a gene the receiver's output depends on, so that the transplant is expressed
+	var candidates []int
+	for i, active := range activeGenes(receiver.program, receiver.bestIndex) {
+		if active {
+			candidates = append(candidates, i)
+		}
+	}
+	poz := candidates[rand.Intn(len(candidates))]
+
+	for i, active := range activeGenes(donor.program, poz) {
+		if !active {
+			continue
+		}
+		gene := donor.program[i]
+		offspring.program[i] = gene
+		if gene.op >= m.numVariables {
+			offspring.constants[gene.op-m.numVariables] = donor.constants[gene.op-m.numVariables]
 		}
 	}
 }
@@ -1076,52 +1195,62 @@ func (m *Mep) uniformCrossover(parent1, parent2, offspring1, offspring2 *chromos
 	}
 }
 
-func (m *Mep) tournamentSelection(subPop, tournamentSize int) int {
+func (m *Mep) mutation(aChromosome *chromosome) {
 
-	p := rand.Intn(m.subPopSize)
-	for i := 1; i < tournamentSize; i++ {
-		r := rand.Intn(m.subPopSize)
-		if m.pop[subPop][r].fitness < m.pop[subPop][p].fitness {
-			p = r
+	if m.mutationPipeline == nil {
+		m.resample(aChromosome, m.mutationProbability)
+		return
+	}
+	for _, step := range m.mutationPipeline {
+		switch step.Operator {
+		case Resample:
+			m.resample(aChromosome, step.Probability)
+		case PerturbConstants:
+			m.perturbConstants(aChromosome, step.Probability, step.Scale)
+		case SwapOperator:
+			m.swapOperators(aChromosome, step.Probability)
+		case ShiftAddress:
+			m.shiftAddresses(aChromosome, step.Probability, step.Scale)
+		case InsertGene:
+			m.insertGene(aChromosome, step.Probability)
 		}
 	}
-	return p
 }
 
-func (m *Mep) mutation(aChromosome *chromosome) {
+func (m *Mep) resample(aChromosome *chromosome, mutationProbability float64) {
 
 	// mutate each symbol with the given probability
 	// first gene must be a variable or constant
-	if rand.Float64() < m.mutationProbability {
+	if rand.Float64() < mutationProbability {
 		aChromosome.program[0].op = m.randomTerminal()
 	}
 
 	for i := 1; i < m.codeLength; i++ {
 
-		if rand.Float64() < m.mutationProbability {
+		if rand.Float64() < mutationProbability {
 			aChromosome.program[i].op = m.randomCode(i)
 		}
 
-		if rand.Float64() < m.mutationProbability {
+		if rand.Float64() < mutationProbability {
 			aChromosome.program[i].adr1 = m.randomAdr(i)
 		}
 
-		if rand.Float64() < m.mutationProbability {
+		if rand.Float64() < mutationProbability {
 			aChromosome.program[i].adr2 = m.randomAdr(i)
 		}
 
-		if rand.Float64() < m.mutationProbability {
+		if rand.Float64() < mutationProbability {
 			aChromosome.program[i].adr3 = m.randomAdr(i)
 		}
 
-		if rand.Float64() < m.mutationProbability {
+		if rand.Float64() < mutationProbability {
 			aChromosome.program[i].adr4 = m.randomAdr(i)
 		}
 	}
 
 	// mutate the constants
 	for c := 0; c < m.numConstants; c++ {
-		if rand.Float64() < m.mutationProbability {
+		if rand.Float64() < mutationProbability {
 			aChromosome.constants[c] = m.randomConstant()
 		}
 	}
@@ -1137,5 +1266,10 @@ func (m *Mep) copyChromosome(source, dest *chromosome) {
 		dest.constants[i] = source.constants[i]
 	}
 	dest.fitness = source.fitness
+	dest.error = source.error
 	dest.bestIndex = source.bestIndex
+	dest.errors = source.errors // never modified, only replaced
+	dest.size = source.size
+	dest.rank = source.rank
+	dest.crowding = source.crowding
 }
@@ -9,6 +9,7 @@ Usage:
   mep -v | -version
 	mep -o | -oper
   mep [options] <filename>|testdata
+  mep export [export options] <model file>
 
 Options:
   -h -help         			print help
@@ -23,17 +24,59 @@ Options:
 	-seed=<int>           sets random number seed (default=unixNano time)
 	-fitness=<float>     	sets fitness threshold to stop evolving
 	-mp=<mutationProb>    sets mutation probability
+	-mutation=<step[,step]> sets the mutation pipeline (default=resample:<mutationProb>), steps are
+	                      resample:p, perturb:p[:scale], swap:p, shift:p[:distance], insert:p
 	-cp=<crossoverProb>		sets crossover probability
+	-crossover=<type>     crossover type: onepoint, twopoint, npoint[:n], uniform, subtree (default=onepoint)
 	-const=num,min,max		sets random constant parameters (-const=num,min,max[,(e|pi|<fixed>)])
+	-constopt=k,n,evals[,method] tunes the constants of the best k individuals every n generations, with at
+	                      most evals evaluations each, by method nm (Nelder-Mead, default), adam or lm
+	                      (Levenberg-Marquardt)
+	-memetic=k,n,steps    improves the best k individuals every n generations by hill climbing, keeping the
+	                      single gene mutations (at most steps each) that improve the fitness
 	-enable=<op[,op]>     enables operators (comma separated list)
 	-disable=<op[,op]>    disables operators (comma separated list)
+	-opweights=<op:w[,op:w]> sets operator weights (default=1), e.g. add:5,mul:5,tan:1
+	-replacement=<mode>   replacement of the population: steady, generational[:elitism], mu+lambda[:lambda],
+	                      mu,lambda[:lambda], parent (default=steady)
+	-selection=<scheme>   parent selection: tournament[:size], roulette, rank[:pressure], truncation[:fraction],
+	                      lexicase[:epsilon], double[:size[:pressure]] (default=tournament:2)
+	-parsimony=<coef>     adds coef times the number of active genes to the fitness used for selection (default=0)
+	-tiebreak             prefers the smaller of outputs and individuals of equal fitness
+	-maxsize=<n>          maximum number of active genes of an output (default=0, no limit)
+	-maxdepth=<n>         maximum depth of the expression of an output (default=0, no limit)
+	-pareto               multi-objective search of accurate and small expressions (NSGA-II), printing the
+	                      Pareto front of error and complexity
+	-pareto-out=<file>    saves the Pareto front as CSV (error,complexity,expression)
+	-opadapt=<rate>       adapts the operator weights to improving offspring (default=0, off)
+	-seed-expr=<file>     seeds the population with expressions (one per line)
+	-seed-model=<file[,file]> seeds the population with saved models
+	-seed-frac=<float>    fraction of each sub-population to seed (default=0.1)
+	-save=<file>          saves the best model
+	-simplify             print the simplified best expression
+	-derive=<label>       print the derivative of the best expression with respect to a variable,
+	                      checked against finite differences on the training data
+	-fixed-output         use the last gene as output (faster evaluation of large data)
+	-domain=<policy>      handling of domain errors like division by zero: repair, repair-all, protect, penalize (default=repair)
+	-numfmt=<verb>        format of constants in expressions, e.g. %g or %.3f (default=full precision)
+
+Export options:
+	-format=<format>      output format: code, dot, latex, mathml (default=code)
+	-lang=<lang>          target language of code: go, c, python, javascript, sql (default=go)
+	-pkg=<name>           package name of generated go code (default=model)
+	-name=<name>          function name (default=Model)
+	-numpy                vectorised python code using numpy
+	-named                use the labels as parameter names
+	-out=<file>           output file (default=stdout)
 */
 package main
 
 import (
+	"encoding/csv"
 	"flag"
 	"fmt"
 	"github.com/markcheno/go-mep"
+	"io/ioutil"
 	"log"
 	"math"
 	"math/rand"
@@ -60,11 +103,34 @@ type mepFlags struct {
 	mutationProbability  float64
 	enable               string
 	disable              string
+	operWeights          string
+	selection            string
+	replacement          string
+	crossover            string
+	mutation             string
+	operAdaptation       float64
 	constants            string
+	constOptimization    string
+	localSearch          string
+	sizePenalty          float64
+	sizeTieBreak         bool
+	maxSize              int
+	maxDepth             int
+	pareto               bool
+	paretoOut            string
+	seedExpr             string
+	seedModel            string
+	seedFraction         float64
+	save                 string
 	operators            bool
 	version              bool
 	td                   bool
 	summary              bool
+	simplify             bool
+	derive               string
+	numberFormat         string
+	fixedOutput          bool
+	domainPolicy         string
 	regression           bool
 }
 
@@ -83,11 +149,34 @@ func main() {
 	flag.Float64Var(&flags.fitnessThreshold, "fitness", 0.01, "fitness threshold")
 	flag.Float64Var(&flags.mutationProbability, "mp", 0.1, "mutation probability")
 	flag.Float64Var(&flags.crossoverProbability, "cp", 0.9, "crossover probability")
+	flag.StringVar(&flags.mutation, "mutation", "", "mutation pipeline: resample:p, perturb:p[:scale], swap:p, shift:p[:distance], insert:p")
+	flag.StringVar(&flags.crossover, "crossover", "onepoint", "crossover type: onepoint, twopoint, npoint[:n], uniform, subtree")
 	flag.StringVar(&flags.enable, "enable", "", "list of operators to enable")
 	flag.StringVar(&flags.disable, "disable", "", "list of operators to disable")
+	flag.StringVar(&flags.operWeights, "opweights", "", "operator weights: op:weight[,op:weight]")
+	flag.StringVar(&flags.replacement, "replacement", "steady", "replacement: steady, generational[:elitism], mu+lambda[:lambda], mu,lambda[:lambda], parent")
+	flag.StringVar(&flags.selection, "selection", "tournament:2", "parent selection: tournament[:size], roulette, rank[:pressure], truncation[:fraction], lexicase[:epsilon], double[:size[:pressure]]")
+	flag.Float64Var(&flags.sizePenalty, "parsimony", 0, "size penalty coefficient")
+	flag.BoolVar(&flags.sizeTieBreak, "tiebreak", false, "break fitness ties by size")
+	flag.IntVar(&flags.maxSize, "maxsize", 0, "maximum number of active genes of an output (0=no limit)")
+	flag.IntVar(&flags.maxDepth, "maxdepth", 0, "maximum expression depth of an output (0=no limit)")
+	flag.BoolVar(&flags.pareto, "pareto", false, "multi-objective search of accurate and small expressions")
+	flag.StringVar(&flags.paretoOut, "pareto-out", "", "save the Pareto front as CSV to file")
+	flag.Float64Var(&flags.operAdaptation, "opadapt", 0, "operator weight adaptation rate (0=off)")
+	flag.StringVar(&flags.constOptimization, "constopt", "", "constant optimization: best,every,evaluations[,nm|adam|lm]")
+	flag.StringVar(&flags.localSearch, "memetic", "", "local search: best,every,steps")
 	flag.StringVar(&flags.constants, "const", "0,0,0", "constants: num,min,max[,(e|pi|<fixed>)]")
+	flag.StringVar(&flags.seedExpr, "seed-expr", "", "file of expressions (one per line) to seed the population with")
+	flag.StringVar(&flags.seedModel, "seed-model", "", "list of saved models to seed the population with")
+	flag.Float64Var(&flags.seedFraction, "seed-frac", 0.1, "fraction of each sub-population to seed")
+	flag.StringVar(&flags.save, "save", "", "save the best model to file")
 	flag.BoolVar(&flags.td, "td", false, "print testdata")
 	flag.BoolVar(&flags.summary, "summary", false, "print summary only")
+	flag.BoolVar(&flags.simplify, "simplify", false, "print the simplified best expression")
+	flag.StringVar(&flags.derive, "derive", "", "print the derivative of the best expression with respect to the variable")
+	flag.BoolVar(&flags.fixedOutput, "fixed-output", false, "use the last gene as output (faster evaluation of large data)")
+	flag.StringVar(&flags.domainPolicy, "domain", "repair", "handling of domain errors: repair, repair-all, protect or penalize")
+	flag.StringVar(&flags.numberFormat, "numfmt", "", "format of constants in expressions, e.g. %g or %.3f (default full precision)")
 	flag.BoolVar(&flags.regression, "regression", true, "regression problem (classification=false)")
 	flag.BoolVar(&flags.version, "v", false, "print version")
 	flag.BoolVar(&flags.version, "version", false, "print version")
@@ -118,6 +207,11 @@ func main() {
 		fmt.Println("  booth")
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		export(os.Args[2:])
+		os.Exit(0)
+	}
+
 	flag.Parse()
 
 	if flags.version {
@@ -191,6 +285,93 @@ func main() {
 	}
 
 	m.SetProb(flags.mutationProbability, flags.crossoverProbability)
+	if flags.constOptimization > "" {
+		var opt [3]int
+		tmp := strings.Split(flags.constOptimization, ",")
+		if len(tmp) == len(opt)+1 {
+			switch tmp[len(opt)] {
+			case "nm":
+			case "adam":
+				m.SetConstOptimizer(mep.Adam)
+			case "lm":
+				m.SetConstOptimizer(mep.LevenbergMarquardt)
+			default:
+				log.Fatalf("invalid constant optimization: %s", flags.constOptimization)
+			}
+			tmp = tmp[:len(opt)]
+		}
+		if len(tmp) != len(opt) {
+			log.Fatalf("invalid constant optimization: %s", flags.constOptimization)
+		}
+		for i := range opt {
+			var err error
+			if opt[i], err = strconv.Atoi(tmp[i]); err != nil || opt[i] < 0 {
+				log.Fatalf("invalid constant optimization: %s", flags.constOptimization)
+			}
+		}
+		m.SetConstOptimization(opt[0], opt[1], opt[2])
+	}
+
+	if flags.localSearch > "" {
+		var opt [3]int
+		tmp := strings.Split(flags.localSearch, ",")
+		if len(tmp) != len(opt) {
+			log.Fatalf("invalid local search: %s", flags.localSearch)
+		}
+		for i := range opt {
+			var err error
+			if opt[i], err = strconv.Atoi(tmp[i]); err != nil || opt[i] < 0 {
+				log.Fatalf("invalid local search: %s", flags.localSearch)
+			}
+		}
+		m.SetLocalSearch(opt[0], opt[1], opt[2])
+	}
+
+	if flags.sizePenalty < 0 || flags.maxSize < 0 || flags.maxDepth < 0 {
+		log.Fatal("invalid parsimony options")
+	}
+	if flags.sizePenalty > 0 {
+		m.SetSizePenalty(flags.sizePenalty)
+	}
+	if flags.sizeTieBreak {
+		m.SetSizeTieBreak(true)
+	}
+	if flags.maxSize > 0 || flags.maxDepth > 0 {
+		m.SetSizeLimit(flags.maxSize, flags.maxDepth)
+	}
+
+	if flags.pareto || flags.paretoOut > "" {
+		m.SetPareto(true)
+	}
+
+	if flags.mutation > "" {
+		m.SetMutationPipeline(mutationPipeline(flags.mutation)...)
+	}
+	crossover := strings.SplitN(flags.crossover, ":", 2)
+	switch crossover[0] {
+	case "onepoint":
+		m.SetCrossover(mep.OneCutPoint, flags.crossoverProbability)
+	case "twopoint":
+		m.SetCrossover(mep.TwoPoint, flags.crossoverProbability)
+	case "npoint":
+		m.SetCrossover(mep.NPoint, flags.crossoverProbability)
+		if len(crossover) > 1 {
+			points, err := strconv.Atoi(crossover[1])
+			if err != nil || points < 1 {
+				log.Fatalf("invalid crossover: %s", flags.crossover)
+			}
+			m.SetCrossoverPoints(points)
+		}
+	case "uniform":
+		m.SetCrossover(mep.Uniform, flags.crossoverProbability)
+	case "subtree":
+		m.SetCrossover(mep.Subtree, flags.crossoverProbability)
+	default:
+		log.Fatalf("invalid crossover: %s", flags.crossover)
+	}
+	if len(crossover) > 1 && crossover[0] != "npoint" {
+		log.Fatalf("invalid crossover: %s", flags.crossover)
+	}
 
 	for _, op := range strings.Split(flags.enable, ",") {
 		m.SetOper(op, true)
@@ -200,6 +381,25 @@ func main() {
 		m.SetOper(op, false)
 	}
 
+	if flags.operWeights > "" {
+		known := "," + strings.Join(m.Oper(true), ",") + ","
+		for _, item := range strings.Split(flags.operWeights, ",") {
+			tmp := strings.Split(item, ":")
+			if len(tmp) != 2 || !strings.Contains(known, ","+tmp[0]+",") {
+				log.Fatalf("invalid operator weight: %s", item)
+			}
+			weight, err := strconv.ParseFloat(tmp[1], 64)
+			if err != nil || weight < 0 {
+				log.Fatalf("invalid operator weight: %s", item)
+			}
+			m.SetOperWeight(tmp[0], weight)
+		}
+	}
+
+	if flags.operAdaptation > 0 {
+		m.SetOperAdaptation(flags.operAdaptation)
+	}
+
 	if flags.constants > "" {
 		var fixed []float64
 		tmp := strings.Split(flags.constants, ",")
@@ -223,6 +423,56 @@ func main() {
 	}
 
 	m.SetPop(flags.subPopSize, flags.numSubPops, flags.codeLen)
+	if flags.fixedOutput {
+		m.SetFixedOutput(true)
+	}
+	switch flags.domainPolicy {
+	case "repair":
+	case "repair-all":
+		m.SetDomainPolicy(mep.RepairAll)
+	case "protect":
+		m.SetDomainPolicy(mep.Protect)
+	case "penalize":
+		m.SetDomainPolicy(mep.Penalize)
+	default:
+		log.Fatalf("invalid domain policy %q", flags.domainPolicy)
+	}
+	m.SetNumberFormat(flags.numberFormat)
+	m.SetSelection(selection(flags.selection))
+	replacement(m, flags.replacement, flags.subPopSize)
+
+	if flags.seedExpr > "" || flags.seedModel > "" {
+		var seeds []*mep.Model
+		if flags.seedExpr > "" {
+			content, err := ioutil.ReadFile(flags.seedExpr)
+			if err != nil {
+				log.Fatal(err)
+			}
+			for _, line := range strings.Split(string(content), "\n") {
+				line = strings.TrimSpace(line)
+				if line == "" || strings.HasPrefix(line, "#") {
+					continue
+				}
+				md, err := m.ParseExpr(line)
+				if err != nil {
+					log.Fatal(err)
+				}
+				seeds = append(seeds, md)
+			}
+		}
+		if flags.seedModel > "" {
+			for _, filename := range strings.Split(flags.seedModel, ",") {
+				md, err := mep.LoadModel(filename)
+				if err != nil {
+					log.Fatal(err)
+				}
+				seeds = append(seeds, md)
+			}
+		}
+		if err := m.SetSeedModels(seeds, flags.seedFraction); err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	if flags.td {
 		m.PrintTestData()
@@ -232,5 +482,280 @@ func main() {
 	fmt.Printf("Elapsed time: %s\n", elapsed)
 	fmt.Printf("Solution after %d generations:\n", gens)
 	m.PrintBest()
+	best, mean := m.EffectiveSize()
+	fmt.Printf("Effective size: best=%d, mean=%.1f of %d genes\n", best, mean, flags.codeLen)
+	if flags.constOptimization > "" {
+		improved, gain := m.ConstImprovement()
+		fmt.Printf("Constant optimization: %d individuals improved, total fitness gain %f\n", improved, gain)
+	}
+	if flags.localSearch > "" {
+		improved, gain := m.LocalSearchImprovement()
+		fmt.Printf("Local search: %d individuals improved, total fitness gain %f\n", improved, gain)
+	}
+	if invalid := m.InvalidEvals(); invalid > 0 {
+		fmt.Printf("Invalid evaluations: %d in the last generation\n", invalid)
+	}
 	//m.PrintTestData()
+
+	if flags.simplify {
+		fmt.Printf("simplified='%s'\n", m.BestExprSimplified())
+	}
+
+	if flags.derive > "" {
+		derivative, maxError, err := m.BestDerivative(flags.derive)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("d/d%s='%s'\n", flags.derive, derivative.Expr(flags.numberFormat))
+		fmt.Printf("Finite difference check: max error %g\n", maxError)
+	}
+
+	if flags.pareto {
+		fmt.Println("Pareto front:")
+		for _, point := range m.ParetoFront() {
+			fmt.Printf("error=%f complexity=%d expr='%s'\n", point.Error, point.Complexity, point.Expr)
+		}
+	}
+	if flags.paretoOut > "" {
+		if err := saveParetoFront(m.ParetoFront(), flags.paretoOut); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if flags.save > "" {
+		if err := m.BestModel().Save(flags.save); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// saveParetoFront - write the front as CSV, with a header
+func saveParetoFront(front []mep.ParetoPoint, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	w.Write([]string{"error", "complexity", "expression"})
+	for _, point := range front {
+		w.Write([]string{strconv.FormatFloat(point.Error, 'g', -1, 64), strconv.Itoa(point.Complexity), point.Expr})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// export - translate a saved model into source code
+func export(args []string) {
+
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "code", "output format (code, dot, latex, mathml)")
+	lang := fs.String("lang", "go", "target language of code (go, c, python, javascript, sql)")
+	pkg := fs.String("pkg", "model", "package name of generated go code")
+	name := fs.String("name", "Model", "function name")
+	named := fs.Bool("named", false, "use the labels as parameter names")
+	numpy := fs.Bool("numpy", false, "vectorised python code using numpy")
+	out := fs.String("out", "", "output file (default stdout)")
+	fs.Usage = func() {
+		fmt.Println("Usage:")
+		fmt.Println("  mep export [options] <model file>")
+		fmt.Println("Options:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	md, err := mep.LoadModel(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var src string
+	switch *format {
+	case "code":
+		src = exportCode(md, *lang, *pkg, *name, *named, *numpy)
+	case "dot":
+		src = md.Dot()
+	case "latex":
+		src = md.Latex()
+	case "mathml":
+		src = md.MathML()
+	default:
+		log.Fatalf("unsupported format: %s", *format)
+	}
+	if !strings.HasSuffix(src, "\n") {
+		src += "\n"
+	}
+
+	if *out == "" {
+		fmt.Print(src)
+	} else if err := ioutil.WriteFile(*out, []byte(src), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func exportCode(md *mep.Model, lang, pkg, name string, named, numpy bool) string {
+
+	var emitter mep.Emitter
+	switch lang {
+	case "go":
+		emitter = &mep.GoEmitter{Package: pkg, Name: name, Named: named}
+	case "c":
+		emitter = &mep.CEmitter{Name: name, Named: named}
+	case "python":
+		emitter = &mep.PythonEmitter{Name: name, Named: named, Numpy: numpy}
+	case "javascript", "js":
+		emitter = &mep.JSEmitter{Name: name, Named: named}
+	case "sql":
+		emitter = &mep.SQLEmitter{}
+	default:
+		log.Fatalf("unsupported language: %s", lang)
+	}
+
+	src, err := md.Export(emitter)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return src
+}
+
+// mutationPipeline - the mutation steps given as name:probability[:scale]
+func mutationPipeline(spec string) []mep.MutationStep {
+	operators := map[string]mep.MutationOperator{
+		"resample": mep.Resample,
+		"perturb":  mep.PerturbConstants,
+		"swap":     mep.SwapOperator,
+		"shift":    mep.ShiftAddress,
+		"insert":   mep.InsertGene,
+	}
+	var steps []mep.MutationStep
+	for _, item := range strings.Split(spec, ",") {
+		tmp := strings.Split(item, ":")
+		operator, ok := operators[tmp[0]]
+		if !ok || len(tmp) < 2 || len(tmp) > 3 || len(tmp) == 3 && operator != mep.PerturbConstants && operator != mep.ShiftAddress {
+			log.Fatalf("invalid mutation step: %s", item)
+		}
+		step := mep.MutationStep{Operator: operator}
+		var err error
+		step.Probability, err = strconv.ParseFloat(tmp[1], 64)
+		if err != nil || step.Probability < 0 || step.Probability > 1 {
+			log.Fatalf("invalid mutation step: %s", item)
+		}
+		if len(tmp) == 3 {
+			step.Scale, err = strconv.ParseFloat(tmp[2], 64)
+			if err != nil || step.Scale < 0 {
+				log.Fatalf("invalid mutation step: %s", item)
+			}
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// replacement - set the replacement mode given as name[:parameter]
+func replacement(m *mep.Mep, spec string, subPopSize int) {
+	tmp := strings.SplitN(spec, ":", 2)
+	param := -1
+	if len(tmp) > 1 {
+		var err error
+		param, err = strconv.Atoi(tmp[1])
+		if err != nil || param < 0 {
+			log.Fatalf("invalid replacement: %s", spec)
+		}
+	}
+	switch tmp[0] {
+	case "steady", "parent":
+		if param >= 0 {
+			log.Fatalf("invalid replacement: %s", spec)
+		}
+		if tmp[0] == "parent" {
+			m.SetReplacement(mep.ReplaceParent)
+		}
+	case "generational":
+		m.SetReplacement(mep.Generational)
+		if param >= subPopSize {
+			log.Fatalf("invalid replacement: %s, elitism must be less than the population size", spec)
+		}
+		if param >= 0 {
+			m.SetElitism(param)
+		}
+	case "mu+lambda", "mu,lambda":
+		if tmp[0] == "mu+lambda" {
+			m.SetReplacement(mep.MuPlusLambda)
+		} else {
+			m.SetReplacement(mep.MuCommaLambda)
+			if param >= 0 && param < subPopSize {
+				log.Fatalf("invalid replacement: %s, lambda must be at least the population size", spec)
+			}
+		}
+		if param >= 0 {
+			m.SetOffspringSize(param)
+		}
+	default:
+		log.Fatalf("invalid replacement: %s", spec)
+	}
+}
+
+// selection - the selection scheme given as name[:parameter], or double[:size[:pressure]]
+func selection(spec string) mep.Selection {
+	tmp := strings.SplitN(spec, ":", 2)
+	param := func(def, min, max float64) float64 {
+		if len(tmp) == 1 {
+			return def
+		}
+		v, err := strconv.ParseFloat(tmp[1], 64)
+		if err != nil || v < min || v > max {
+			log.Fatalf("invalid selection: %s", spec)
+		}
+		return v
+	}
+	switch tmp[0] {
+	case "tournament":
+		size := param(2, 1, math.MaxInt32)
+		if size != math.Trunc(size) {
+			log.Fatalf("invalid selection: %s", spec)
+		}
+		return mep.TournamentSelection{Size: int(size)}
+	case "roulette":
+		if len(tmp) > 1 {
+			log.Fatalf("invalid selection: %s", spec)
+		}
+		return mep.RouletteSelection{}
+	case "rank":
+		return mep.RankSelection{Pressure: param(1.5, 1, 2)}
+	case "truncation":
+		fraction := param(0.5, 0, 1)
+		if fraction == 0 {
+			log.Fatalf("invalid selection: %s", spec)
+		}
+		return mep.TruncationSelection{Fraction: fraction}
+	case "lexicase":
+		return mep.LexicaseSelection{Epsilon: param(0, 0, math.Inf(1))}
+	case "double":
+		s := mep.DoubleTournamentSelection{Size: 2, Pressure: 1.4}
+		if len(tmp) > 1 {
+			params := strings.Split(tmp[1], ":")
+			size, err := strconv.Atoi(params[0])
+			if err != nil || size < 1 || len(params) > 2 {
+				log.Fatalf("invalid selection: %s", spec)
+			}
+			s.Size = size
+			if len(params) == 2 {
+				s.Pressure, err = strconv.ParseFloat(params[1], 64)
+				if err != nil || s.Pressure < 1 || s.Pressure > 2 {
+					log.Fatalf("invalid selection: %s", spec)
+				}
+			}
+		}
+		return s
+	}
+	log.Fatalf("invalid selection: %s", spec)
+	return nil
 }
@@ -0,0 +1,163 @@
+package mep
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// Model - a compiled MEP program that can be evaluated independently of the population
+type Model struct {
+	Labels    []string // variable names, in the order of the input columns
+	program   program
+	constants constants
+	output    int
+}
+
+// ParseModel - compile an infix expression (as returned by BestExpr) into a model.
+// Variables are referenced by their labels.
+func ParseModel(expr string, labels []string) (*Model, error) {
+	n, err := parseTree(expr, labels)
+	if err != nil {
+		return nil, err
+	}
+	md := &Model{Labels: labels}
+	md.program, md.constants, md.output = compile(n, len(labels))
+	return md, nil
+}
+
+// ParseExpr - compile an infix expression using the variables of the training data
+func (m *Mep) ParseExpr(expr string) (*Model, error) {
+	return ParseModel(expr, m.td.Labels[:m.numVariables])
+}
+
+// BestModel - return the best individual of the population as a model
+func (m *Mep) BestModel() *Model {
+	return m.model(m.pop[m.bestPop][0])
+}
+
+func (m *Mep) model(c chromosome) *Model {
+	md := &Model{Labels: m.td.Labels[:m.numVariables], output: c.bestIndex}
+	md.program = make(program, len(c.program))
+	copy(md.program, c.program)
+	md.constants = make(constants, len(c.constants))
+	copy(md.constants, c.constants)
+	return md
+}
+
+func (md *Model) tree() *node {
+	return buildTree(md.program, md.constants, len(md.Labels), md.output)
+}
+
+// String - infix representation of the model, in the same syntax as BestExpr
+func (md *Model) String() string {
+	return md.tree().format("", md.Labels)
+}
+
+// Expr - infix representation of the model with constants formatted as by SetNumberFormat
+func (md *Model) Expr(numberFormat string) string {
+	return md.tree().formatNumbers("", md.Labels, numberFormat)
+}
+
+// Size - number of genes in the model's program
+func (md *Model) Size() int {
+	return len(md.program)
+}
+
+// Predict - evaluate the model on each row of data, computing only the genes the output depends on
+func (md *Model) Predict(data [][]float64) []float64 {
+
+	numVariables := len(md.Labels)
+	active := activeGenes(md.program, md.output)
+	results := make([][]float64, len(md.program))
+	for i := 0; i <= md.output; i++ {
+		if !active[i] {
+			continue
+		}
+		results[i] = make([]float64, len(data))
+		gene := md.program[i]
+		if gene.op < 0 {
+			execute(gene, results, results[i])
+		} else if gene.op < numVariables {
+			for k := range data {
+				results[i][k] = data[k][gene.op]
+			}
+		} else {
+			for k := range data {
+				results[i][k] = md.constants[gene.op-numVariables]
+			}
+		}
+	}
+	return results[md.output]
+}
+
+// Eval - evaluate the model on a single row
+func (md *Model) Eval(x []float64) float64 {
+	return md.Predict([][]float64{x})[0]
+}
+
+// savedModel - file format of a saved model
+type savedModel struct {
+	Expr      string         `json:"expr"`
+	Labels    []string       `json:"labels"`
+	Operators map[int]string `json:"operators"` // names of the operator codes of the program
+	Program   [][5]int       `json:"program"`
+	Constants []float64      `json:"constants"`
+	Output    int            `json:"output"`
+}
+
+// Save - write the model to a file (json), without the genes that do not contribute to the output
+func (md *Model) Save(filename string) error {
+	md = md.Prune()
+	s := savedModel{Expr: md.String(), Labels: md.Labels, Operators: map[int]string{}, Constants: md.constants, Output: md.output}
+	for _, gene := range md.program {
+		if gene.op < 0 {
+			s.Operators[gene.op] = operatorOf(gene.op).Name()
+		}
+		s.Program = append(s.Program, [5]int{gene.op, gene.adr1, gene.adr2, gene.adr3, gene.adr4})
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+// LoadModel - read a model written by Save. The operators are found by name, and must be registered
+// (see RegisterOperator); files without operator names use the builtin codes.
+func LoadModel(filename string) (*Model, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var s savedModel
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	md := &Model{Labels: s.Labels, constants: s.Constants, output: s.Output}
+	for i, g := range s.Program {
+		gene := instruction{g[0], g[1], g[2], g[3], g[4]}
+		if gene.op < 0 && s.Operators != nil {
+			o, found := operatorByName(s.Operators[gene.op])
+			if !found {
+				return nil, fmt.Errorf("%s: unknown operator %q in gene %d", filename, s.Operators[gene.op], i)
+			}
+			gene.op = o.op
+		}
+		if gene.op < -len(registeredOperators) || gene.op >= len(md.Labels)+len(md.constants) {
+			return nil, fmt.Errorf("%s: invalid op %d in gene %d", filename, gene.op, i)
+		}
+		if gene.op < 0 {
+			for _, adr := range []int{gene.adr1, gene.adr2, gene.adr3, gene.adr4} {
+				if adr < 0 || adr >= i {
+					return nil, fmt.Errorf("%s: invalid address in gene %d", filename, i)
+				}
+			}
+		}
+		md.program = append(md.program, gene)
+	}
+	if md.output < 0 || md.output >= len(md.program) {
+		return nil, fmt.Errorf("%s: invalid output gene %d", filename, md.output)
+	}
+	return md, nil
+}
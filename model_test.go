@@ -0,0 +1,53 @@
+package mep
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSeedExpr(t *testing.T) {
+
+	m := New(NewPythagorean(20), TotalErrorFF)
+	m.SetOper("sqrt", true)
+	ok(t, m.SetSeedExpr([]string{"sqrt(x0*x0+x1*x1)"}, 0.1))
+	if m.BestFitness() > 1e-9 {
+		t.Errorf("seeded population best fitness = %f", m.BestFitness())
+	}
+
+	// constants must fit in the configured constants
+	if err := m.SetSeedExpr([]string{"x0*(2.000000)"}, 0.1); err == nil {
+		t.Error("expected error for seed with too many constants")
+	}
+	m.SetConst(nil, 1, -1, 1)
+	ok(t, m.SetSeedExpr([]string{"x0*(2.000000)"}, 0.1))
+}
+
+func TestSaveLoadModel(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "mep")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "model.json")
+
+	m := New(NewPythagorean(20), TotalErrorFF)
+	m.SetConst(nil, 2, -1, 1)
+	m.Solve(10, 0, false)
+
+	md := m.BestModel()
+	ok(t, md.Save(filename))
+	loaded, err := LoadModel(filename)
+	ok(t, err)
+	equals(t, md.String(), loaded.String())
+	equals(t, md.Predict(m.td.Train), loaded.Predict(m.td.Train))
+
+	// addresses must refer to previous genes
+	for _, program := range []string{"[[0,0,0,0,0],[-1,0,1,0,0]]", "[[0,0,0,0,0],[-1,0,-1,0,0]]"} {
+		json := `{"labels":["x0"],"program":` + program + `,"constants":[],"output":1}`
+		ok(t, ioutil.WriteFile(filename, []byte(json), 0644))
+		if _, err := LoadModel(filename); err == nil {
+			t.Errorf("expected error loading program %s", program)
+		}
+	}
+}
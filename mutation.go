@@ -0,0 +1,171 @@
+package mep
+
+import (
+	"math/rand"
+)
+
+// MutationOperator - kind of change made by a step of the mutation pipeline
+type MutationOperator int
+
+const (
+	// Resample - replace op codes, addresses and constants by random ones, with the step's probability
+	// for each of them (the default pipeline, with the mutation probability)
+	Resample MutationOperator = iota
+	// PerturbConstants - add gaussian noise to each constant with the step's probability, the standard
+	// deviation being Scale times the range of the random constants (default 0.1); fixed constants are kept
+	PerturbConstants
+	// SwapOperator - replace each operator with the step's probability by another one of the same arity,
+	// keeping its arguments
+	SwapOperator
+	// ShiftAddress - move one of the arguments of each operator with the step's probability
+	// to a gene at most Scale positions away (default 1)
+	ShiftAddress
+	// InsertGene - with the step's probability, insert a copy of a gene or a random gene before it,
+	// shifting the following genes down to the first gene not used by the output gene recorded in the
+	// chromosome, which is dropped. The addresses are remapped, so that the expression of that gene is
+	// unchanged. After crossover it is the output gene of a parent, so an offspring's expression may
+	// still change; with a fixed output it is always the last gene.
+	InsertGene
+)
+
+// MutationStep - a mutation operator of the pipeline, with its probability and scale (see MutationOperator)
+type MutationStep struct {
+	Operator    MutationOperator
+	Probability float64
+	Scale       float64
+}
+
+// SetMutationPipeline - mutation steps applied in order to each offspring, replacing the default
+// Resample with the mutation probability; no steps restore the default
+func (m *Mep) SetMutationPipeline(steps ...MutationStep) {
+	for _, step := range steps {
+		if step.Operator < Resample || step.Operator > InsertGene {
+			panic("invalid mutation operator")
+		}
+		if step.Probability < 0.0 || step.Probability > 1.0 {
+			panic("invalid mutation probability")
+		}
+		if step.Scale < 0 {
+			panic("invalid mutation scale")
+		}
+	}
+	m.mutationPipeline = append([]MutationStep(nil), steps...)
+}
+
+// perturbConstants - gaussian noise on the constants that are not fixed
+func (m *Mep) perturbConstants(c *chromosome, probability, scale float64) {
+	if scale == 0 {
+		scale = 0.1
+	}
+	if m.randConstantsMax > m.randConstantsMin {
+		scale *= m.randConstantsMax - m.randConstantsMin
+	}
+	for i := range c.constants {
+		if rand.Float64() >= probability || m.isFixedConstant(c.constants[i]) {
+			continue
+		}
+		c.constants[i] += rand.NormFloat64() * scale
+	}
+}
+
+func (m *Mep) isFixedConstant(v float64) bool {
+	for _, fixed := range m.fixedConstants {
+		if v == fixed {
+			return true
+		}
+	}
+	return false
+}
+
+// swapOperators - replace operators by enabled operators of the same arity, chosen in proportion to the weights
+func (m *Mep) swapOperators(c *chromosome, probability float64) {
+	for i := range c.program {
+		op := c.program[i].op
+		if op >= 0 || rand.Float64() >= probability {
+			continue
+		}
+		arity := operatorOf(op).Arity()
+		weights := make([]float64, len(m.operators))
+		for k, o := range m.operators {
+			if o.enabled && o.op != op && o.Arity() == arity {
+				weights[k] = o.weight
+			}
+		}
+		k := spin(weights)
+		if weights[k] == 0 {
+			continue // no other operator of that arity
+		}
+		op = m.operators[k].op
+		if protected, ok := protectedOperators[op]; ok && m.domainPolicy == Protect {
+			op = protected
+		}
+		c.program[i].op = op
+	}
+}
+
+// shiftAddresses - move an argument of operators to a nearby gene
+func (m *Mep) shiftAddresses(c *chromosome, probability, scale float64) {
+	distance := int(scale)
+	if distance < 1 {
+		distance = 1
+	}
+	for i := 1; i < len(c.program); i++ {
+		gene := &c.program[i]
+		if gene.op >= 0 || rand.Float64() >= probability {
+			continue
+		}
+		adr := []*int{&gene.adr1, &gene.adr2, &gene.adr3, &gene.adr4}[rand.Intn(operatorOf(gene.op).Arity())]
+		shift := 1 + rand.Intn(distance)
+		if rand.Intn(2) == 0 {
+			shift = -shift
+		}
+		*adr += shift
+		if *adr < 0 {
+			*adr = 0
+		}
+		if *adr >= i {
+			*adr = i - 1
+		}
+	}
+}
+
+// insertGene - insert a copy of gene k or a random gene at k, dropping the first gene after it
+// that the gene at bestIndex does not use, and remapping the addresses of the shifted genes
+func (m *Mep) insertGene(c *chromosome, probability float64) {
+	if rand.Float64() >= probability {
+		return
+	}
+	k := 1 + rand.Intn(m.codeLength-1)
+	var active []bool
+	if c.bestIndex >= 0 {
+		active = activeGenes(c.program, c.bestIndex)
+	} else {
+		active = make([]bool, m.codeLength)
+	}
+	dropped := k
+	for dropped < m.codeLength && active[dropped] {
+		dropped++
+	}
+	if dropped == m.codeLength {
+		return // the output uses all the genes after k
+	}
+
+	gene := c.program[k]
+	if rand.Intn(2) == 0 {
+		gene = instruction{op: m.randomCode(k), adr1: m.randomAdr(k), adr2: m.randomAdr(k), adr3: m.randomAdr(k), adr4: m.randomAdr(k)}
+	}
+	copy(c.program[k+1:dropped+1], c.program[k:dropped])
+	c.program[k] = gene
+
+	for i := k + 1; i < m.codeLength; i++ {
+		for _, adr := range []*int{&c.program[i].adr1, &c.program[i].adr2, &c.program[i].adr3, &c.program[i].adr4} {
+			// addresses of the dropped gene are only used by unused genes, they keep pointing to its place
+			if *adr >= k && *adr < dropped {
+				*adr++
+			}
+		}
+	}
+	if c.bestIndex >= k && c.bestIndex < dropped {
+		c.bestIndex++
+	}
+}
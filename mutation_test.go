@@ -0,0 +1,93 @@
+package mep
+
+import (
+	"math"
+	"testing"
+)
+
+func TestInsertGene(t *testing.T) {
+
+	for _, fixedOutput := range []bool{false, true} {
+		m := New(NewPythagorean(10), TotalErrorFF)
+		m.SetConst([]float64{math.Pi}, 3, -1, 1)
+		m.SetPop(10, 1, 20)
+		m.SetFixedOutput(fixedOutput)
+		changed := 0
+		for trial := 0; trial < 50; trial++ {
+			c := m.randomChromosome(0)
+			before := m.model(c).String()
+			program := append(program(nil), c.program...)
+			m.insertGene(&c, 1)
+			// the expression of the gene at bestIndex is unchanged and the addresses still valid
+			equals(t, before, m.model(c).String())
+			for i, gene := range c.program {
+				if i > 0 && (gene.adr1 >= i || gene.adr2 >= i || gene.adr3 >= i || gene.adr4 >= i) {
+					t.Fatalf("invalid address in gene %d: %v", i, gene)
+				}
+			}
+			for i := range program {
+				if program[i] != c.program[i] {
+					changed++
+					break
+				}
+			}
+		}
+		if changed < 10 {
+			t.Errorf("genes inserted in %d programs of 50", changed)
+		}
+	}
+}
+
+func TestMutationOperators(t *testing.T) {
+
+	m := New(NewPythagorean(10), TotalErrorFF)
+	m.SetConst([]float64{math.Pi}, 3, -1, 1)
+	m.SetPop(10, 1, 20)
+	m.SetOper("sin", true)
+	m.SetOper("sqrt", true)
+
+	for trial := 0; trial < 20; trial++ {
+		c := m.randomChromosome(0)
+		mutated := c
+		mutated.program = append(program(nil), c.program...)
+		mutated.constants = append(constants(nil), c.constants...)
+
+		m.swapOperators(&mutated, 1)
+		for i, gene := range mutated.program {
+			if gene.op < 0 {
+				equals(t, operatorOf(c.program[i].op).Arity(), operatorOf(gene.op).Arity())
+				equals(t, c.program[i].adr1, gene.adr1)
+			} else {
+				equals(t, c.program[i], gene)
+			}
+		}
+
+		copy(mutated.program, c.program)
+		m.shiftAddresses(&mutated, 1, 2)
+		for i, gene := range mutated.program {
+			equals(t, c.program[i].op, gene.op)
+			for k, adr := range []int{gene.adr1, gene.adr2, gene.adr3, gene.adr4} {
+				old := []int{c.program[i].adr1, c.program[i].adr2, c.program[i].adr3, c.program[i].adr4}[k]
+				if gene.op >= 0 && adr != old || adr < 0 || i > 0 && adr >= i || adr-old > 2 || old-adr > 2 {
+					t.Fatalf("gene %d address %d shifted from %d to %d", i, k, old, adr)
+				}
+			}
+		}
+
+		m.perturbConstants(&mutated, 1, 0.1)
+		for i, v := range mutated.constants {
+			if c.constants[i] == math.Pi {
+				equals(t, math.Pi, v)
+			} else if v == c.constants[i] {
+				t.Errorf("constant %d not perturbed", i)
+			}
+		}
+	}
+
+	m.SetMutationPipeline(MutationStep{Operator: Resample, Probability: 0.05}, MutationStep{Operator: PerturbConstants, Probability: 0.2},
+		MutationStep{Operator: SwapOperator, Probability: 0.05}, MutationStep{Operator: ShiftAddress, Probability: 0.05, Scale: 3},
+		MutationStep{Operator: InsertGene, Probability: 0.2})
+	m.Solve(10, 0, false)
+	m.SetMutationPipeline()
+	equals(t, 0, len(m.mutationPipeline))
+}
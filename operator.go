@@ -0,0 +1,452 @@
+package mep
+
+import (
+	"fmt"
+	"math"
+	"unicode"
+)
+
+// Operator - a function of 1 to 4 arguments that genes can apply to the results of previous genes
+type Operator interface {
+	// Name - identifier used in expressions, by SetOper and by Oper
+	Name() string
+	// Arity - number of arguments, from 1 to 4
+	Arity() int
+	// Eval - compute out[k] from args[0][k], ..., args[Arity()-1][k] for every row k
+	Eval(args [][]float64, out []float64)
+	// Format - the operator applied to the translated arguments in an export target
+	// ("go", "c", "python", "numpy", "javascript", "sql", "latex" or "mathml"),
+	// false if the target is not supported
+	Format(target string, args []string) (string, bool)
+}
+
+// DomainChecker - optionally implemented by operators that are undefined for some arguments.
+// A gene whose arguments are out of the domain is mutated into a terminal during evaluation.
+type DomainChecker interface {
+	// InDomain - whether every row of the arguments is valid
+	InDomain(args [][]float64) bool
+}
+
+type operator struct {
+	Operator
+	op      int
+	enabled bool
+	weight  float64 // relative probability among the enabled operators
+}
+
+// builtin - operator defined by this package; exports translate it themselves,
+// after expanding the operators defined in terms of others
+type builtin struct {
+	name   string
+	arity  int
+	eval   func(args [][]float64, out []float64)
+	domain func(args [][]float64) bool
+	expand func(args []*node) *node
+}
+
+func (o *builtin) Name() string {
+	return o.name
+}
+
+func (o *builtin) Arity() int {
+	return o.arity
+}
+
+func (o *builtin) Eval(args [][]float64, out []float64) {
+	o.eval(args, out)
+}
+
+func (o *builtin) Format(target string, args []string) (string, bool) {
+	return "", false
+}
+
+func (o *builtin) InDomain(args [][]float64) bool {
+	return o.domain == nil || o.domain(args)
+}
+
+// math1 - operator applying f to each row
+func math1(name string, f func(float64) float64) *builtin {
+	return &builtin{name: name, arity: 1, eval: func(args [][]float64, out []float64) {
+		a := args[0]
+		for k := range out {
+			out[k] = f(a[k])
+		}
+	}}
+}
+
+// builtin operators, indexed by -op-1
+var builtinOperators = []operator{
+	{&builtin{name: "add", arity: 2, eval: func(args [][]float64, out []float64) {
+		a, b := args[0], args[1]
+		for k := range out {
+			out[k] = a[k] + b[k]
+		}
+	}}, -1, true, 1},
+	{&builtin{name: "sub", arity: 2, eval: func(args [][]float64, out []float64) {
+		a, b := args[0], args[1]
+		for k := range out {
+			out[k] = a[k] - b[k]
+		}
+	}}, -2, true, 1},
+	{&builtin{name: "mul", arity: 2, eval: func(args [][]float64, out []float64) {
+		a, b := args[0], args[1]
+		for k := range out {
+			out[k] = a[k] * b[k]
+		}
+	}}, -3, true, 1},
+	{&builtin{name: "div", arity: 2, eval: func(args [][]float64, out []float64) {
+		a, b := args[0], args[1]
+		for k := range out {
+			out[k] = a[k] / b[k]
+		}
+	}, domain: func(args [][]float64) bool {
+		for _, b := range args[1] {
+			if math.Abs(b) < 1e-6 { // a small constant
+				return false
+			}
+		}
+		return true
+	}}, -4, true, 1},
+	{math1("sin", math.Sin), -5, false, 1},
+	{math1("cos", math.Cos), -6, false, 1},
+	{math1("tan", math.Tan), -7, false, 1},
+	{math1("exp", math.Exp), -8, false, 1},
+	{math1("log", math.Log), -9, false, 1},
+	{math1("sqrt", math.Sqrt), -10, false, 1},
+	{math1("abs", math.Abs), -11, false, 1},
+	{&builtin{name: "max", arity: 2, eval: func(args [][]float64, out []float64) {
+		a, b := args[0], args[1]
+		for k := range out {
+			if a[k] > b[k] {
+				out[k] = a[k]
+			} else {
+				out[k] = b[k]
+			}
+		}
+	}}, -12, false, 1},
+	{&builtin{name: "min", arity: 2, eval: func(args [][]float64, out []float64) {
+		a, b := args[0], args[1]
+		for k := range out {
+			if a[k] < b[k] {
+				out[k] = a[k]
+			} else {
+				out[k] = b[k]
+			}
+		}
+	}}, -13, false, 1},
+	{&builtin{name: "ifgtz", arity: 3, eval: func(args [][]float64, out []float64) {
+		a, b, c := args[0], args[1], args[2]
+		for k := range out {
+			if a[k] > 0.0 {
+				out[k] = b[k]
+			} else {
+				out[k] = c[k]
+			}
+		}
+	}}, -14, false, 1},
+	{&builtin{name: "ifltz", arity: 3, eval: func(args [][]float64, out []float64) {
+		a, b, c := args[0], args[1], args[2]
+		for k := range out {
+			if a[k] < 0.0 {
+				out[k] = b[k]
+			} else {
+				out[k] = c[k]
+			}
+		}
+	}}, -15, false, 1},
+	{&builtin{name: "ifgt", arity: 4, eval: func(args [][]float64, out []float64) {
+		a, b, c, d := args[0], args[1], args[2], args[3]
+		for k := range out {
+			if a[k] > b[k] {
+				out[k] = c[k]
+			} else {
+				out[k] = d[k]
+			}
+		}
+	}}, -16, false, 1},
+	{&builtin{name: "iflt", arity: 4, eval: func(args [][]float64, out []float64) {
+		a, b, c, d := args[0], args[1], args[2], args[3]
+		for k := range out {
+			if a[k] < b[k] {
+				out[k] = c[k]
+			} else {
+				out[k] = d[k]
+			}
+		}
+	}}, -17, false, 1},
+	{&builtin{name: "ifbgt", arity: 2, eval: func(args [][]float64, out []float64) {
+		a, b := args[0], args[1]
+		for k := range out {
+			if a[k] > b[k] {
+				out[k] = 1.0
+			} else {
+				out[k] = 0.0
+			}
+		}
+	}}, -18, false, 1},
+	{&builtin{name: "ifblt", arity: 2, eval: func(args [][]float64, out []float64) {
+		a, b := args[0], args[1]
+		for k := range out {
+			if a[k] < b[k] {
+				out[k] = 1.0
+			} else {
+				out[k] = 0.0
+			}
+		}
+	}}, -19, false, 1},
+	{&builtin{name: "and", arity: 2, eval: func(args [][]float64, out []float64) {
+		a, b := args[0], args[1]
+		for k := range out {
+			if a[k] > 0.0 && b[k] > 0.0 {
+				out[k] = 1.0
+			} else {
+				out[k] = 0.0
+			}
+		}
+	}}, -20, false, 1},
+	{&builtin{name: "or", arity: 2, eval: func(args [][]float64, out []float64) {
+		a, b := args[0], args[1]
+		for k := range out {
+			if a[k] > 0.0 || b[k] > 0.0 {
+				out[k] = 1.0
+			} else {
+				out[k] = 0.0
+			}
+		}
+	}}, -21, false, 1},
+	{&builtin{name: "pow", arity: 2, eval: func(args [][]float64, out []float64) {
+		a, b := args[0], args[1]
+		for k := range out {
+			out[k] = math.Pow(a[k], b[k])
+		}
+	}}, -22, false, 1},
+	{math1("pow10", func(a float64) float64 { return math.Pow10(int(a)) }), -23, false, 1},
+	{math1("log10", math.Log10), -24, false, 1},
+	{math1("log2", math.Log2), -25, false, 1},
+	{math1("floor", math.Floor), -26, false, 1},
+	{math1("ceil", math.Ceil), -27, false, 1},
+	{math1("inv", func(a float64) float64 { return 1.0 / a }), -28, false, 1},
+	{math1("square", func(a float64) float64 { return a * a }), -29, false, 1},
+	{math1("tanh", math.Tanh), -30, false, 1},
+	{math1("sigmoid", func(a float64) float64 { return 1.0 / (1.0 + math.Exp(-a)) }), -31, false, 1},
+	{math1("atan", math.Atan), -32, false, 1},
+	{&builtin{name: "atan2", arity: 2, eval: func(args [][]float64, out []float64) {
+		a, b := args[0], args[1]
+		for k := range out {
+			out[k] = math.Atan2(a[k], b[k])
+		}
+	}}, -33, false, 1},
+	{&builtin{name: "fmod", arity: 2, eval: func(args [][]float64, out []float64) {
+		a, b := args[0], args[1]
+		for k := range out {
+			out[k] = math.Mod(a[k], b[k])
+		}
+	}}, -34, false, 1},
+	{&builtin{name: "hypot", arity: 2, eval: func(args [][]float64, out []float64) {
+		a, b := args[0], args[1]
+		for k := range out {
+			out[k] = math.Hypot(a[k], b[k])
+		}
+	}}, -35, false, 1},
+	{math1("neg", func(a float64) float64 { return -a }), -36, false, 1},
+	{math1("cube", func(a float64) float64 { return a * a * a }), -37, false, 1},
+	{math1("cbrt", math.Cbrt), -38, false, 1},
+	{math1("gauss", func(a float64) float64 { return math.Exp(-(a * a)) }), -39, false, 1},
+	{math1("sign", sign), -40, false, 1},
+	{math1("step", func(a float64) float64 {
+		if a > 0 {
+			return 1.0
+		}
+		return 0.0
+	}), -41, false, 1},
+	// protected versions, defined for every argument, internal to the Protect policy
+	{&builtin{name: "pdiv", arity: 2, eval: func(args [][]float64, out []float64) {
+		a, b := args[0], args[1]
+		for k := range out {
+			if math.Abs(b[k]) < 1e-6 {
+				out[k] = 1.0
+			} else {
+				out[k] = a[k] / b[k]
+			}
+		}
+	}, expand: func(a []*node) *node {
+		return protect(a[1], 1, operatorNodeOf(-4, a...))
+	}}, -42, false, 1},
+	{&builtin{name: "pinv", arity: 1, eval: func(args [][]float64, out []float64) {
+		a := args[0]
+		for k := range out {
+			if math.Abs(a[k]) < 1e-6 {
+				out[k] = 1.0
+			} else {
+				out[k] = 1.0 / a[k]
+			}
+		}
+	}, expand: func(a []*node) *node {
+		return protect(a[0], 1, operatorNodeOf(-28, a...))
+	}}, -43, false, 1},
+	{protectedLog("plog", math.Log, -9), -44, false, 1},
+	{protectedLog("plog10", math.Log10, -24), -45, false, 1},
+	{protectedLog("plog2", math.Log2, -25), -46, false, 1},
+	{&builtin{name: "psqrt", arity: 1, eval: func(args [][]float64, out []float64) {
+		a := args[0]
+		for k := range out {
+			out[k] = math.Sqrt(math.Abs(a[k]))
+		}
+	}, expand: func(a []*node) *node {
+		return operatorNodeOf(-10, operatorNodeOf(-11, a[0]))
+	}}, -47, false, 1},
+	{&builtin{name: "ppow", arity: 2, eval: func(args [][]float64, out []float64) {
+		a, b := args[0], args[1]
+		for k := range out {
+			if math.Abs(a[k]) < 1e-6 {
+				out[k] = 0.0
+			} else {
+				out[k] = math.Pow(math.Abs(a[k]), b[k])
+			}
+		}
+	}, expand: func(a []*node) *node {
+		return protect(a[0], 0, operatorNodeOf(-22, operatorNodeOf(-11, a[0]), a[1]))
+	}}, -48, false, 1},
+	{&builtin{name: "pfmod", arity: 2, eval: func(args [][]float64, out []float64) {
+		a, b := args[0], args[1]
+		for k := range out {
+			if math.Abs(b[k]) < 1e-6 {
+				out[k] = 1.0
+			} else {
+				out[k] = math.Mod(a[k], b[k])
+			}
+		}
+	}, expand: func(a []*node) *node {
+		return protect(a[1], 1, operatorNodeOf(-34, a...))
+	}}, -49, false, 1},
+}
+
+// protectedOperators - the protected version of each operator with a restricted domain
+var protectedOperators = map[int]int{-4: -42, -28: -43, -9: -44, -24: -45, -25: -46, -10: -47, -22: -48, -34: -49}
+
+// protectedLog - log|a|, 0 near 0
+func protectedLog(name string, f func(float64) float64, op int) *builtin {
+	return &builtin{name: name, arity: 1, eval: func(args [][]float64, out []float64) {
+		a := args[0]
+		for k := range out {
+			if math.Abs(a[k]) < 1e-6 {
+				out[k] = 0.0
+			} else {
+				out[k] = f(math.Abs(a[k]))
+			}
+		}
+	}, expand: func(a []*node) *node {
+		return protect(a[0], 0, operatorNodeOf(op, operatorNodeOf(-11, a[0])))
+	}}
+}
+
+// protect - the tree of ifgt(1e-6, abs(x), value, n), computing a protected operator with the plain ones
+func protect(x *node, value float64, n *node) *node {
+	return operatorNodeOf(-16, constantNodeOf(1e-6), operatorNodeOf(-11, x), constantNodeOf(value), n)
+}
+
+// sign - -1, 0 or 1; zeros and NaN are returned unchanged
+func sign(a float64) float64 {
+	switch {
+	case a > 0:
+		return 1.0
+	case a < 0:
+		return -1.0
+	}
+	return a
+}
+
+// registeredOperators - the builtin operators followed by those added by RegisterOperator, indexed by -op-1
+var registeredOperators = append([]operator(nil), builtinOperators...)
+
+// RegisterOperator - make an operator available to the populations created afterwards, where it is enabled.
+// Operators are identified by name in expressions and saved models, so a model using it can be loaded
+// once it is registered again, for instance from an init function.
+func RegisterOperator(o Operator) error {
+	name := o.Name()
+	if name == "" || name == "iif" || !unicode.IsLetter([]rune(name)[0]) {
+		return fmt.Errorf("invalid operator name %q", name)
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return fmt.Errorf("invalid operator name %q", name)
+		}
+	}
+	if _, ok := operatorByName(name); ok {
+		return fmt.Errorf("operator %s already registered", name)
+	}
+	if o.Arity() < 1 || o.Arity() > 4 {
+		return fmt.Errorf("operator %s: invalid arity %d, should be 1 to 4", name, o.Arity())
+	}
+	registeredOperators = append(registeredOperators, operator{o, -len(registeredOperators) - 1, true, 1})
+	return nil
+}
+
+// operatorByName - find a registered operator by name
+func operatorByName(name string) (operator, bool) {
+	for _, o := range registeredOperators {
+		if o.Name() == name {
+			return o, true
+		}
+	}
+	return operator{}, false
+}
+
+// operatorOf - the operator of an operator gene
+func operatorOf(op int) operator {
+	return registeredOperators[-op-1]
+}
+
+// formatOperator - a registered operator applied to the translated arguments, for exports
+func formatOperator(target, name string, args []string) (string, error) {
+	if o, ok := operatorByName(name); ok {
+		if s, ok := o.Format(target, args); ok {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("%s: unsupported operator %s", target, name)
+}
+
+// funcOperator - operator defined by NewOperator
+type funcOperator struct {
+	name    string
+	arity   int
+	f       func(x ...float64) float64
+	formats map[string]string
+}
+
+// NewOperator - an Operator applying f to the arguments of each row. formats maps export targets
+// to fmt templates of the translated arguments, e.g. {"c": "hypot(%s, %s)", "python": "math.hypot(%s, %s)"}.
+func NewOperator(name string, arity int, f func(x ...float64) float64, formats map[string]string) Operator {
+	return &funcOperator{name, arity, f, formats}
+}
+
+func (o *funcOperator) Name() string {
+	return o.name
+}
+
+func (o *funcOperator) Arity() int {
+	return o.arity
+}
+
+func (o *funcOperator) Eval(args [][]float64, out []float64) {
+	x := make([]float64, o.arity)
+	for k := range out {
+		for i := range x {
+			x[i] = args[i][k]
+		}
+		out[k] = o.f(x...)
+	}
+}
+
+func (o *funcOperator) Format(target string, args []string) (string, bool) {
+	format, ok := o.formats[target]
+	if !ok {
+		return "", false
+	}
+	a := make([]interface{}, len(args))
+	for i, arg := range args {
+		a[i] = arg
+	}
+	return fmt.Sprintf(format, a...), true
+}
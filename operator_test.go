@@ -0,0 +1,160 @@
+package mep
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func init() {
+	logistic := NewOperator("logistic", 1, func(x ...float64) float64 {
+		return 1 / (1 + math.Exp(-x[0]))
+	}, map[string]string{
+		"go":         "(1 / (1 + math.Exp(-%s)))",
+		"c":          "(1.0 / (1.0 + exp(-%s)))",
+		"python":     "(1.0 / (1.0 + math.exp(-%s)))",
+		"javascript": "(1 / (1 + Math.exp(-%s)))",
+		"latex":      `\sigma\left(%s\right)`,
+	})
+	if err := RegisterOperator(logistic); err != nil {
+		panic(err)
+	}
+}
+
+func TestRegisterOperator(t *testing.T) {
+
+	for _, o := range []Operator{
+		NewOperator("add", 2, nil, nil),
+		NewOperator("iif", 3, nil, nil),
+		NewOperator("2x", 1, nil, nil),
+		NewOperator("a.b", 1, nil, nil),
+		NewOperator("nullary", 0, nil, nil),
+		NewOperator("five", 5, nil, nil),
+	} {
+		if RegisterOperator(o) == nil {
+			t.Errorf("expected error registering %s", o.Name())
+		}
+	}
+
+	m := New(NewPythagorean(20), TotalErrorFF)
+	equals(t, true, strings.Contains(strings.Join(m.Oper(false), ","), "logistic"))
+
+	md, err := m.ParseExpr("logistic(x0-x1)*2")
+	ok(t, err)
+	equals(t, "logistic(x0-x1)*2", md.String())
+	equals(t, 1.0, md.Eval([]float64{3, 3}))
+	equals(t, `\sigma\left(x_{0} - x_{1}\right) \cdot 2`, md.Latex())
+	equals(t, true, strings.Contains(md.MathML(), "<mi>logistic</mi>"))
+
+	src, err := md.Export(&CEmitter{Name: "model"})
+	ok(t, err)
+	equals(t, true, strings.Contains(src, "return ((1.0 / (1.0 + exp(-(x[0] - x[1])))) * 2.0);"))
+	equals(t, true, strings.Contains(md.ExportGo("model", "Model", false), "import \"math\""))
+	_, err = md.Export(&SQLEmitter{})
+	equals(t, "sql: unsupported operator logistic", err.Error())
+
+	// saved models refer to the operators by name, whatever their codes
+	dir, err := ioutil.TempDir("", "mep")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "model.json")
+	ok(t, md.Save(filename))
+	loaded, err := LoadModel(filename)
+	ok(t, err)
+	equals(t, md.String(), loaded.String())
+	program := `"program":[[0,0,0,0,0],[1,0,0,0,0],[-101,0,1,0,0],[-100,2,0,0,0]],"constants":[],"output":3}`
+	ok(t, ioutil.WriteFile(filename, []byte(`{"labels":["x0","x1"],"operators":{"-100":"logistic","-101":"sub"},`+program), 0644))
+	loaded, err = LoadModel(filename)
+	ok(t, err)
+	equals(t, "logistic(x0-x1)", loaded.String())
+	ok(t, ioutil.WriteFile(filename, []byte(`{"labels":["x0","x1"],"operators":{"-100":"unknown","-101":"sub"},`+program), 0644))
+	if _, err := LoadModel(filename); err == nil {
+		t.Error("expected error loading an unknown operator")
+	}
+
+	// evolution only uses the registered operator
+	for _, name := range m.Oper(false) {
+		m.SetOper(name, name == "logistic")
+	}
+	m.SetPop(20, 1, 10)
+	m.Solve(2, 0, false)
+	for _, gene := range m.pop[m.bestPop][0].program {
+		if gene.op < 0 {
+			equals(t, "logistic", operatorOf(gene.op).Name())
+		}
+	}
+}
+
+func TestOperWeights(t *testing.T) {
+
+	m := New(NewPythagorean(20), TotalErrorFF)
+	for _, name := range m.Oper(true) {
+		m.SetOper(name, name == "add" || name == "mul" || name == "tan")
+	}
+	m.SetOperWeight("add", 5)
+	m.SetOperWeight("mul", 5)
+	equals(t, map[string]float64{"add": 5, "mul": 5, "tan": 1}, m.OperWeights())
+
+	counts := map[string]int{}
+	for i := 0; i < 11000; i++ {
+		counts[operatorOf(m.randomOperator()).Name()]++
+	}
+	if counts["tan"] < 700 || counts["tan"] > 1300 || counts["add"] < 4500 || counts["mul"] < 4500 {
+		t.Errorf("operator counts %v", counts)
+	}
+
+	// adaptation keeps the sum of the weights and every operator available
+	m.SetOperWeight("sqrt", 3)
+	m.SetOper("sqrt", true)
+	m.SetOperAdaptation(0.5)
+	m.Solve(10, 0, false)
+	sum := 0.0
+	for _, w := range m.OperWeights() {
+		if w < 0.5*0.1*14/4 {
+			t.Errorf("weights %v", m.OperWeights())
+		}
+		sum += w
+	}
+	if math.Abs(sum-14) > 1e-9 {
+		t.Errorf("weights %v", m.OperWeights())
+	}
+}
+
+func TestNewBuiltinOperators(t *testing.T) {
+
+	labels := []string{"x0", "x1"}
+	x := []float64{-8, 6}
+	tests := []struct {
+		expr   string
+		result float64
+	}{
+		{"tanh(x0)", math.Tanh(-8)},
+		{"sigmoid(x1)", 1 / (1 + math.Exp(-6))},
+		{"atan(x1)", math.Atan(6)},
+		{"atan2(x0,x1)", math.Atan2(-8, 6)},
+		{"fmod(x0,x1)", -2},
+		{"hypot(x0,x1)", 10},
+		{"neg(x0)", 8},
+		{"cube(x0)", -512},
+		{"cbrt(x0)", -2},
+		{"gauss(x1-x1)", 1},
+		{"sign(x0)", -1},
+		{"sign(x1-x1)", 0},
+		{"step(x0)", 0},
+		{"step(x1)", 1},
+	}
+	for _, test := range tests {
+		md, err := ParseModel(test.expr, labels)
+		ok(t, err)
+		equals(t, test.result, md.Eval(x))
+	}
+
+	m := New(NewPythagorean(20), TotalErrorFF)
+	all := "," + strings.Join(m.Oper(true), ",") + ","
+	for _, name := range []string{"tanh", "sigmoid", "atan", "atan2", "fmod", "hypot", "neg", "cube", "cbrt", "gauss", "sign", "step"} {
+		equals(t, true, strings.Contains(all, ","+name+","))
+	}
+}
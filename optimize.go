@@ -0,0 +1,392 @@
+package mep
+
+import (
+	"math"
+	"sort"
+)
+
+// ConstOptimizer - local search method of the constant optimisation
+type ConstOptimizer int
+
+const (
+	// NelderMead - derivative-free simplex method on the fitness (default)
+	NelderMead ConstOptimizer = iota
+	// Adam - gradient descent with adaptive moments on the mean squared error
+	Adam
+	// LevenbergMarquardt - damped least squares on the errors
+	LevenbergMarquardt
+)
+
+// SetConstOptimization - every `every` generations, tune the constants used by the best `best`
+// individuals of each sub-population (see SetConstOptimizer), stopping after `evaluations`
+// evaluations of the program per individual; the fixed constants are kept, and the tuned
+// constants only when they improve the fitness.
+// A zero argument disables the optimisation (default).
+func (m *Mep) SetConstOptimization(best, every, evaluations int) {
+	if best < 0 || every < 0 || evaluations < 0 {
+		panic("invalid constant optimization")
+	}
+	m.constOptBest = best
+	m.constOptEvery = every
+	m.constOptEvaluations = evaluations
+}
+
+// SetConstOptimizer - method of the constant optimisation (default NelderMead). Adam and
+// LevenbergMarquardt differentiate the program with respect to the constants, and minimise
+// the squared errors whatever the fitness function.
+func (m *Mep) SetConstOptimizer(optimizer ConstOptimizer) {
+	if optimizer < NelderMead || optimizer > LevenbergMarquardt {
+		panic("invalid constant optimizer")
+	}
+	m.constOptimizer = optimizer
+}
+
+// ConstImprovement - number of individuals whose fitness was improved by the constant optimisation
+// since the population was initialised, and the sum of the improvements
+func (m *Mep) ConstImprovement() (int, float64) {
+	return m.constOptImproved, m.constOptGain
+}
+
+// optimizeConstants - tune the constants of the best individuals of each sub-population
+func (m *Mep) optimizeConstants() {
+	for p := range m.pop {
+		best := m.constOptBest
+		if best > m.subPopSize {
+			best = m.subPopSize
+		}
+		for i := 0; i < best; i++ {
+			m.optimizeChromosome(p, &m.pop[p][i])
+		}
+		sort.Sort(m.pop[p])
+	}
+}
+
+// optimizeChromosome - tune the constants of the chromosome's output, keeping the result if it is better
+func (m *Mep) optimizeChromosome(p int, c *chromosome) {
+
+	// the constants used by the output, except the fixed ones
+	var indexes []int
+	used := map[int]bool{}
+	for i, active := range activeGenes(c.program, c.bestIndex) {
+		op := c.program[i].op
+		if active && op >= m.numVariables && !used[op] {
+			used[op] = true
+			if !m.isFixedConstant(c.constants[op-m.numVariables]) {
+				indexes = append(indexes, op-m.numVariables)
+			}
+		}
+	}
+	if len(indexes) == 0 || math.IsNaN(c.fitness) || math.IsInf(c.fitness, 0) {
+		return
+	}
+
+	md := m.model(*c)
+	fitness := func(x []float64) float64 {
+		for k, index := range indexes {
+			md.constants[index] = x[k]
+		}
+		f := m.ff(md.Predict(m.td.Train), m.td.Target)
+		if math.IsNaN(f) {
+			return math.Inf(1)
+		}
+		return f
+	}
+	x := make([]float64, len(indexes))
+	for k, index := range indexes {
+		x[k] = c.constants[index]
+	}
+	scale := 0.1 * math.Abs(m.randConstantsMax-m.randConstantsMin)
+	if !(scale > 0) {
+		scale = 0.1
+	}
+	// errors and their derivatives with respect to the constants
+	wrt := make([]int, len(indexes))
+	for k, index := range indexes {
+		wrt[k] = m.numVariables + index
+	}
+	errors := func(x []float64) ([]float64, [][]float64) {
+		for k, index := range indexes {
+			md.constants[index] = x[k]
+		}
+		signal, derivs := md.dual(m.td.Train, wrt)
+		for k := range signal {
+			signal[k] -= m.td.Target[k]
+		}
+		return signal, derivs
+	}
+
+	switch m.constOptimizer {
+	case NelderMead:
+		x, _ = nelderMead(fitness, x, scale, m.constOptEvaluations)
+	case Adam:
+		x = adam(errors, x, 0.1*scale, m.constOptEvaluations)
+	case LevenbergMarquardt:
+		x = levenbergMarquardt(errors, x, m.constOptEvaluations)
+	}
+
+	// evaluate the tuned chromosome, which may choose another output
+	tuned := c.clone()
+	for k, index := range indexes {
+		tuned.constants[index] = x[k]
+	}
+	m.eval(m.results[p], &tuned)
+	if better(tuned.fitness, c.fitness) {
+		m.constOptImproved++
+		m.constOptGain += c.fitness - tuned.fitness
+		m.copyChromosome(&tuned, c)
+	}
+}
+
+// nelderMead - minimise f starting from x0 with an initial simplex of the given step,
+// until the simplex collapses or the evaluations are exhausted; returns the best point and value
+func nelderMead(f func([]float64) float64, x0 []float64, step float64, evaluations int) ([]float64, float64) {
+
+	n := len(x0)
+	points := make([][]float64, n+1)
+	values := make([]float64, n+1)
+	evaluate := func(x []float64) float64 {
+		if evaluations <= 0 {
+			return math.Inf(1) // out of budget, the point is never chosen
+		}
+		evaluations--
+		return f(x)
+	}
+	for i := range points {
+		points[i] = append([]float64(nil), x0...)
+		if i > 0 {
+			// relative steps for large coordinates
+			points[i][i-1] += math.Max(step, 0.1*math.Abs(x0[i-1]))
+		}
+		values[i] = evaluate(points[i])
+	}
+
+	centroid := make([]float64, n)
+	along := func(t float64) []float64 { // centroid + t*(centroid-worst)
+		x := make([]float64, n)
+		for j := range x {
+			x[j] = centroid[j] + t*(centroid[j]-points[n][j])
+		}
+		return x
+	}
+
+	for evaluations > 0 {
+		// order the simplex, best first
+		sort.Sort(simplex{points, values})
+		if values[n]-values[0] <= 1e-12*math.Abs(values[0]) || spread(points) < 1e-12 {
+			break
+		}
+		for j := range centroid {
+			centroid[j] = 0
+			for i := 0; i < n; i++ {
+				centroid[j] += points[i][j] / float64(n)
+			}
+		}
+
+		reflected := along(1)
+		r := evaluate(reflected)
+		switch {
+		case r < values[0]:
+			expanded := along(2)
+			if e := evaluate(expanded); e < r {
+				points[n], values[n] = expanded, e
+			} else {
+				points[n], values[n] = reflected, r
+			}
+		case r < values[n-1]:
+			points[n], values[n] = reflected, r
+		default:
+			t := -0.5 // inside contraction
+			if r < values[n] {
+				t = 0.5 // outside contraction
+			}
+			contracted := along(t)
+			if c := evaluate(contracted); c < math.Min(r, values[n]) {
+				points[n], values[n] = contracted, c
+			} else {
+				// shrink towards the best point
+				for i := 1; i <= n && evaluations > 0; i++ {
+					for j := range points[i] {
+						points[i][j] = points[0][j] + 0.5*(points[i][j]-points[0][j])
+					}
+					values[i] = evaluate(points[i])
+				}
+			}
+		}
+	}
+	sort.Sort(simplex{points, values})
+	return points[0], values[0]
+}
+
+// spread - largest distance of a coordinate from the first point of the simplex
+func spread(points [][]float64) float64 {
+	d := 0.0
+	for _, x := range points[1:] {
+		for j := range x {
+			d = math.Max(d, math.Abs(x[j]-points[0][j]))
+		}
+	}
+	return d
+}
+
+// simplex - points sorted by their values
+type simplex struct {
+	points [][]float64
+	values []float64
+}
+
+func (s simplex) Len() int {
+	return len(s.values)
+}
+
+func (s simplex) Less(i, j int) bool {
+	return better(s.values[i], s.values[j])
+}
+
+func (s simplex) Swap(i, j int) {
+	s.points[i], s.points[j] = s.points[j], s.points[i]
+	s.values[i], s.values[j] = s.values[j], s.values[i]
+}
+
+// adam - minimise the mean squared errors from x0 by gradient descent with adaptive moments,
+// returning the best point met; errors gives the errors and their derivatives (derivs[d][k])
+func adam(errors func([]float64) ([]float64, [][]float64), x0 []float64, rate float64, iterations int) []float64 {
+
+	const beta1, beta2, epsilon = 0.9, 0.999, 1e-8
+	x := append([]float64(nil), x0...)
+	best := append([]float64(nil), x0...)
+	bestLoss := math.Inf(1)
+	moment := make([]float64, len(x))
+	variance := make([]float64, len(x))
+	for t := 1; t <= iterations; t++ {
+		e, derivs := errors(x)
+		loss := sumOfSquares(e) / float64(len(e))
+		if math.IsNaN(loss) || math.IsInf(loss, 0) {
+			return best
+		}
+		if loss < bestLoss {
+			bestLoss = loss
+			copy(best, x)
+		}
+		for d := range x {
+			g := 0.0
+			for k := range e {
+				g += 2 * e[k] * derivs[d][k] / float64(len(e))
+			}
+			if math.IsNaN(g) || math.IsInf(g, 0) {
+				return best
+			}
+			moment[d] = beta1*moment[d] + (1-beta1)*g
+			variance[d] = beta2*variance[d] + (1-beta2)*g*g
+			corrected := moment[d] / (1 - math.Pow(beta1, float64(t)))
+			correctedVariance := variance[d] / (1 - math.Pow(beta2, float64(t)))
+			x[d] -= rate * corrected / (math.Sqrt(correctedVariance) + epsilon)
+		}
+	}
+	return best
+}
+
+// levenbergMarquardt - minimise the sum of squared errors from x0 by damped Gauss-Newton steps,
+// errors giving the errors and their derivatives (derivs[d][k])
+func levenbergMarquardt(errors func([]float64) ([]float64, [][]float64), x0 []float64, iterations int) []float64 {
+
+	n := len(x0)
+	x := append([]float64(nil), x0...)
+	e, derivs := errors(x)
+	iterations--
+	loss := sumOfSquares(e)
+	lambda := 1e-3
+	for iterations > 0 && loss > 0 && !math.IsInf(loss, 0) {
+		// normal equations (J'J + lambda diag(J'J)) step = -J'e
+		a := make([][]float64, n)
+		g := make([]float64, n)
+		for i := range a {
+			a[i] = make([]float64, n)
+			for j := range a[i] {
+				for k := range e {
+					a[i][j] += derivs[i][k] * derivs[j][k]
+				}
+			}
+			for k := range e {
+				g[i] -= derivs[i][k] * e[k]
+			}
+		}
+		for {
+			if iterations <= 0 || lambda > 1e16 {
+				return x
+			}
+			damped := make([][]float64, n)
+			for i := range a {
+				damped[i] = append([]float64(nil), a[i]...)
+				damped[i][i] += lambda * math.Max(a[i][i], 1e-12)
+			}
+			step, ok := solve(damped, g)
+			if !ok {
+				lambda *= 10
+				continue
+			}
+			next := make([]float64, n)
+			for i := range x {
+				next[i] = x[i] + step[i]
+			}
+			nextErrors, nextDerivs := errors(next)
+			iterations--
+			nextLoss := sumOfSquares(nextErrors)
+			if !(nextLoss < loss) {
+				lambda *= 10
+				continue
+			}
+			converged := loss-nextLoss <= 1e-12*loss
+			x, e, derivs, loss = next, nextErrors, nextDerivs, nextLoss
+			lambda /= 10
+			if converged {
+				return x
+			}
+			break
+		}
+	}
+	return x
+}
+
+func sumOfSquares(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v * v
+	}
+	return sum
+}
+
+// solve - solution of the linear system a x = b by Gaussian elimination with partial pivoting,
+// false if a is singular; a is modified
+func solve(a [][]float64, b []float64) ([]float64, bool) {
+	n := len(b)
+	b = append([]float64(nil), b...)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if a[pivot][col] == 0 || math.IsNaN(a[pivot][col]) {
+			return nil, false
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+		for row := col + 1; row < n; row++ {
+			f := a[row][col] / a[col][col]
+			for j := col; j < n; j++ {
+				a[row][j] -= f * a[col][j]
+			}
+			b[row] -= f * b[col]
+		}
+	}
+	x := make([]float64, n)
+	for row := n - 1; row >= 0; row-- {
+		x[row] = b[row]
+		for j := row + 1; j < n; j++ {
+			x[row] -= a[row][j] * x[j]
+		}
+		x[row] /= a[row][row]
+	}
+	return x, true
+}
@@ -0,0 +1,51 @@
+package mep
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNelderMead(t *testing.T) {
+
+	rosenbrock := func(x []float64) float64 {
+		return 100*math.Pow(x[1]-x[0]*x[0], 2) + math.Pow(1-x[0], 2)
+	}
+	x, value := nelderMead(rosenbrock, []float64{-1.2, 1}, 0.1, 1000)
+	if value > 1e-8 || math.Abs(x[0]-1) > 1e-3 || math.Abs(x[1]-1) > 1e-3 {
+		t.Errorf("rosenbrock minimum %v at %v", value, x)
+	}
+
+	// the budget is respected
+	evaluations := 0
+	nelderMead(func(x []float64) float64 { evaluations++; return rosenbrock(x) }, []float64{-1.2, 1}, 0.1, 20)
+	equals(t, 20, evaluations)
+}
+
+func TestConstOptimization(t *testing.T) {
+
+	// e*x0*x0 + pi*x0
+	m := New(NewSimpleConstantRegression3(20), TotalErrorFF)
+	m.SetConst(nil, 2, -1, 1)
+	m.SetPop(10, 1, 10)
+	m.SetFixedOutput(true)
+	m.SetConstOptimization(1, 1, 300)
+	md, err := m.ParseExpr("2*x0*x0+3*x0")
+	ok(t, err)
+	c := m.seedChromosome(0, md)
+	before := c.fitness
+	m.optimizeChromosome(0, &c)
+	if c.fitness > 1e-3 {
+		t.Errorf("fitness %f after optimization of %f", c.fitness, before)
+	}
+	improved, gain := m.ConstImprovement()
+	equals(t, 1, improved)
+	equals(t, before-c.fitness, gain)
+
+	// the best individual is optimised at each generation
+	ok(t, m.SetSeedExpr([]string{"2*x0*x0+3*x0"}, 1))
+	m.SetProb(0, 0)
+	m.Evolve()
+	if improved, _ = m.ConstImprovement(); improved != 1 || m.BestFitness() > 1e-3 {
+		t.Errorf("fitness %f after optimization", m.BestFitness())
+	}
+}
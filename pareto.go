@@ -0,0 +1,179 @@
+package mep
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// ParetoPoint - a trade-off between the error of an expression (its fitness, without the size penalty)
+// and its complexity (number of active genes), none of the other points being better in both
+type ParetoPoint struct {
+	Error      float64
+	Complexity int
+	Expr       string
+	Model      *Model
+}
+
+// SetPareto - multi-objective search of accurate and small expressions, in the manner of NSGA-II:
+// parents are chosen by tournaments on the non-domination rank and crowding distance of the
+// individuals (replacing the selection), and each sub-population and its offspring are ranked
+// together to make the next generation (replacing the replacement type). The non-dominated
+// individuals of all the sub-populations are kept in an archive (see ParetoFront).
+func (m *Mep) SetPareto(enabled bool) {
+	m.pareto = enabled
+	m.archive = nil
+}
+
+// ParetoFront - the archived trade-offs between error and complexity, by increasing complexity
+func (m *Mep) ParetoFront() []ParetoPoint {
+	front := make([]ParetoPoint, len(m.archive))
+	for i, c := range m.archive {
+		front[i] = ParetoPoint{
+			Error:      c.error,
+			Complexity: countActive(c.program, c.bestIndex),
+			Expr:       m.parse("", c, c.bestIndex),
+			Model:      m.model(c),
+		}
+	}
+	sort.SliceStable(front, func(i, j int) bool {
+		return front[i].Complexity < front[j].Complexity
+	})
+	return front
+}
+
+// dominates - whether a solution with error ea and size sa is at least as good as one with eb and sb
+// in both objectives and better in one; a non-finite error is worse than any finite one
+func dominates(ea float64, sa int, eb float64, sb int) bool {
+	if !finite([]float64{ea}) || !finite([]float64{eb}) {
+		return finite([]float64{ea}) && !finite([]float64{eb})
+	}
+	return ea <= eb && sa <= sb && (ea < eb || sa < sb)
+}
+
+// nondominatedSort - the non-domination rank of each solution (0 for the Pareto front, 1 for the
+// front of the rest, ...) and its crowding distance in its front, infinite at the extremes
+func nondominatedSort(errors []float64, sizes []int) ([]int, []float64) {
+
+	n := len(errors)
+	ranks := make([]int, n)
+	crowding := make([]float64, n)
+	dominated := make([][]int, n) // the solutions each one dominates
+	count := make([]int, n)       // the number of solutions dominating each one
+	var front []int
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if dominates(errors[i], sizes[i], errors[j], sizes[j]) {
+				dominated[i] = append(dominated[i], j)
+			} else if dominates(errors[j], sizes[j], errors[i], sizes[i]) {
+				count[i]++
+			}
+		}
+		if count[i] == 0 {
+			front = append(front, i)
+		}
+	}
+	for rank := 0; len(front) > 0; rank++ {
+		var next []int
+		for _, i := range front {
+			ranks[i] = rank
+			for _, j := range dominated[i] {
+				if count[j]--; count[j] == 0 {
+					next = append(next, j)
+				}
+			}
+		}
+		crowd(front, errors, sizes, crowding)
+		front = next
+	}
+	return ranks, crowding
+}
+
+// crowd - crowding distances of the solutions of a front: the sum over the objectives of the
+// normalised distance between their neighbours
+func crowd(front []int, errors []float64, sizes []int, crowding []float64) {
+	objectives := []func(i int) float64{
+		func(i int) float64 { return errors[i] },
+		func(i int) float64 { return float64(sizes[i]) },
+	}
+	sorted := append([]int(nil), front...)
+	for _, objective := range objectives {
+		sort.SliceStable(sorted, func(a, b int) bool { return objective(sorted[a]) < objective(sorted[b]) })
+		first, last := sorted[0], sorted[len(sorted)-1]
+		crowding[first], crowding[last] = math.Inf(1), math.Inf(1)
+		span := objective(last) - objective(first)
+		if !(span > 0) || math.IsInf(span, 0) {
+			continue
+		}
+		for k := 1; k < len(sorted)-1; k++ {
+			crowding[sorted[k]] += (objective(sorted[k+1]) - objective(sorted[k-1])) / span
+		}
+	}
+}
+
+// rankPareto - non-domination ranks and crowding distances of the individuals of a sub-population
+func (m *Mep) rankPareto(subPop subPopulation) {
+	errors := make([]float64, len(subPop))
+	sizes := make([]int, len(subPop))
+	for i, c := range subPop {
+		errors[i] = c.error
+		sizes[i] = countActive(c.program, c.bestIndex)
+	}
+	ranks, crowding := nondominatedSort(errors, sizes)
+	for i := range subPop {
+		subPop[i].rank = ranks[i]
+		subPop[i].crowding = crowding[i]
+	}
+}
+
+// crowdedTournament - the better of two random individuals by rank, then by crowding distance
+func crowdedTournament(subPop subPopulation) int {
+	a, b := rand.Intn(len(subPop)), rand.Intn(len(subPop))
+	if subPop[b].rank < subPop[a].rank || subPop[b].rank == subPop[a].rank && subPop[b].crowding > subPop[a].crowding {
+		return b
+	}
+	return a
+}
+
+// survivors - the best subPopSize individuals of the merged sub-population and offspring by rank,
+// then by crowding distance, sorted by fitness
+func (m *Mep) survivors(merged subPopulation) subPopulation {
+	m.rankPareto(merged)
+	sort.SliceStable(merged, func(i, j int) bool {
+		a, b := merged[i], merged[j]
+		return a.rank < b.rank || a.rank == b.rank && a.crowding > b.crowding
+	})
+	next := merged[:m.subPopSize:m.subPopSize]
+	sort.Stable(next)
+	return next
+}
+
+// updateArchive - merge the Pareto fronts of the sub-populations into the archive, keeping one
+// individual for each trade-off
+func (m *Mep) updateArchive() {
+	merged := append(subPopulation(nil), m.archive...)
+	for _, subPop := range m.pop {
+		for _, c := range subPop {
+			if finite([]float64{c.error}) {
+				merged = append(merged, c)
+			}
+		}
+	}
+	errors := make([]float64, len(merged))
+	sizes := make([]int, len(merged))
+	for i, c := range merged {
+		errors[i] = c.error
+		sizes[i] = countActive(c.program, c.bestIndex)
+	}
+	ranks, _ := nondominatedSort(errors, sizes)
+	var archive subPopulation
+	seen := map[int]bool{}
+	for i, c := range merged {
+		if ranks[i] == 0 && !seen[sizes[i]] {
+			// on the front, equal sizes have equal errors
+			seen[sizes[i]] = true
+			archive = append(archive, c.clone())
+		}
+	}
+	m.archive = archive
+}
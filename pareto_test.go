@@ -0,0 +1,54 @@
+package mep
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNondominatedSort(t *testing.T) {
+
+	// fronts {0, 1, 2}, {3, 4}, {5}; solution 6 has an infinite error
+	errors := []float64{1, 2, 4, 3, 5, 6, math.Inf(1)}
+	sizes := []int{5, 3, 1, 5, 3, 9, 1}
+	ranks, crowding := nondominatedSort(errors, sizes)
+	equals(t, []int{0, 0, 0, 1, 1, 2, 3}, ranks)
+	equals(t, math.Inf(1), crowding[0])
+	equals(t, math.Inf(1), crowding[2])
+	equals(t, 3.0/3+4.0/4, crowding[1])
+}
+
+func TestPareto(t *testing.T) {
+
+	// the errors of the front are without the size penalty
+	for _, penalty := range []float64{0, 1} {
+		m := New(NewPythagorean(20), TotalErrorFF)
+		m.SetPop(20, 2, 30)
+		m.SetSizePenalty(penalty)
+		m.SetPareto(true)
+		m.Solve(20, 0, false)
+
+		front := m.ParetoFront()
+		if len(front) == 0 {
+			t.Fatalf("empty Pareto front")
+		}
+		for i, point := range front {
+			if i > 0 && (point.Complexity <= front[i-1].Complexity || point.Error >= front[i-1].Error) {
+				t.Errorf("dominated point %v", point)
+			}
+			md, err := m.ParseExpr(point.Expr)
+			ok(t, err)
+			if e := TotalErrorFF(md.Predict(m.td.Train), m.td.Target); math.Abs(e-point.Error) > 1e-6*math.Max(1, e) {
+				t.Errorf("%s: error %f, expected %f", point.Expr, e, point.Error)
+			}
+			equals(t, point.Error, TotalErrorFF(point.Model.Predict(m.td.Train), m.td.Target))
+		}
+
+		// the most accurate point is at least as good as the population
+		if front[len(front)-1].Error > m.BestFitness() {
+			t.Errorf("best fitness %f missing from the front", m.BestFitness())
+		}
+		for _, subPop := range m.pop {
+			equals(t, 20, len(subPop))
+		}
+	}
+}
@@ -0,0 +1,395 @@
+package mep
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type token struct {
+	text string
+	pos  int
+}
+
+// tokenize - split an infix expression into numbers, identifiers and symbols
+func tokenize(s string) ([]token, error) {
+
+	var tokens []token
+	for i := 0; i < len(s); {
+		r := rune(s[i])
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(s) && unicode.IsDigit(rune(s[i+1]))):
+			j := i
+			for j < len(s) && (unicode.IsDigit(rune(s[j])) || s[j] == '.') {
+				j++
+			}
+			// exponent
+			if j < len(s) && (s[j] == 'e' || s[j] == 'E') {
+				k := j + 1
+				if k < len(s) && (s[k] == '+' || s[k] == '-') {
+					k++
+				}
+				if k < len(s) && unicode.IsDigit(rune(s[k])) {
+					for k < len(s) && unicode.IsDigit(rune(s[k])) {
+						k++
+					}
+					j = k
+				}
+			}
+			tokens = append(tokens, token{s[i:j], i})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(s) && (unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j])) || s[j] == '_' || s[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{s[i:j], i})
+			i = j
+		case strings.HasPrefix(s[i:], "&&") || strings.HasPrefix(s[i:], "||"):
+			tokens = append(tokens, token{s[i : i+2], i})
+			i += 2
+		case strings.ContainsRune("+-*/(),<>", r):
+			tokens = append(tokens, token{s[i : i+1], i})
+			i++
+		default:
+			return nil, fmt.Errorf("invalid expression: unexpected %q at %d", s[i], i)
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	labels []string
+}
+
+// parseTree - parse an infix expression, in the syntax produced by parse, into an expression tree
+func parseTree(s string, labels []string) (*node, error) {
+
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, labels: labels}
+	n, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, p.unexpected()
+	}
+	return n, nil
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos].text
+	}
+	return ""
+}
+
+func (p *parser) unexpected() error {
+	if p.pos < len(p.tokens) {
+		return fmt.Errorf("invalid expression: unexpected %q at %d", p.tokens[p.pos].text, p.tokens[p.pos].pos)
+	}
+	return fmt.Errorf("invalid expression: unexpected end")
+}
+
+func (p *parser) expect(text string) error {
+	if p.peek() != text {
+		return p.unexpected()
+	}
+	p.pos++
+	return nil
+}
+
+// literal - consume the bare numbers in values if they are next, each followed by a separator
+func (p *parser) literal(values ...string) bool {
+	if p.pos+2*len(values) > len(p.tokens) {
+		return false
+	}
+	for i, v := range values {
+		f, err := strconv.ParseFloat(p.tokens[p.pos+2*i].text, 64)
+		if err != nil || f != mustParseFloat(v) {
+			return false
+		}
+		if sep := p.tokens[p.pos+2*i+1].text; sep != "," && sep != ")" && sep != "&&" && sep != "||" {
+			return false
+		}
+	}
+	p.pos += 2*len(values) - 1 // leave the last separator
+	return true
+}
+
+func mustParseFloat(s string) float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func operatorNodeOf(op int, args ...*node) *node {
+	return &node{kind: operatorNode, op: op, args: args}
+}
+
+// expr := term { ("+"|"-") term }
+func (p *parser) expr() (*node, error) {
+	n, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "+" || p.peek() == "-" {
+		op := -1
+		if p.peek() == "-" {
+			op = -2
+		}
+		p.pos++
+		rhs, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		n = operatorNodeOf(op, n, rhs)
+	}
+	return n, nil
+}
+
+// term := unary { ("*"|"/") unary }
+func (p *parser) term() (*node, error) {
+	n, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "*" || p.peek() == "/" {
+		op := -3
+		if p.peek() == "/" {
+			op = -4
+		}
+		p.pos++
+		rhs, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		n = operatorNodeOf(op, n, rhs)
+	}
+	return n, nil
+}
+
+// unary := "-" unary | primary
+func (p *parser) unary() (*node, error) {
+	if p.peek() != "-" {
+		return p.primary()
+	}
+	p.pos++
+	n, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	if n.kind == constantNode {
+		n.value = -n.value
+		return n, nil
+	}
+	return operatorNodeOf(-3, &node{kind: constantNode, value: -1}, n), nil
+}
+
+// primary := number | variable | function "(" args ")" | "(" expr ")"
+func (p *parser) primary() (*node, error) {
+
+	if p.pos >= len(p.tokens) {
+		return nil, p.unexpected()
+	}
+	tok := p.tokens[p.pos]
+
+	if tok.text == "(" {
+		p.pos++
+		n, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		return n, p.expect(")")
+	}
+
+	if f, err := strconv.ParseFloat(tok.text, 64); err == nil {
+		p.pos++
+		return &node{kind: constantNode, value: f}, nil
+	}
+
+	if !unicode.IsLetter(rune(tok.text[0])) && tok.text[0] != '_' {
+		return nil, p.unexpected()
+	}
+	p.pos++
+
+	if p.peek() != "(" {
+		for i, label := range p.labels {
+			if label == tok.text {
+				return &node{kind: variableNode, op: i}, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid expression: unknown variable %q at %d", tok.text, tok.pos)
+	}
+	p.pos++
+
+	if tok.text == "iif" {
+		return p.iif()
+	}
+
+	o, ok := operatorByName(tok.text)
+	if !ok {
+		return nil, fmt.Errorf("invalid expression: unknown function %q at %d", tok.text, tok.pos)
+	}
+	n := operatorNodeOf(o.op)
+	for i := 0; i < o.Arity(); i++ {
+		if i > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		n.args = append(n.args, arg)
+	}
+	return n, p.expect(")")
+}
+
+// iif - the conditional operators, after "iif(": a>0,b,c a<0,b,c a>b,c,d a<b,c,d a>b,1,0 a<b,1,0 a>0 &&b>0,1,0 a>0 ||b>0,1,0
+func (p *parser) iif() (*node, error) {
+
+	a, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	cmp := p.peek()
+	if cmp != ">" && cmp != "<" {
+		return nil, p.unexpected()
+	}
+	p.pos++
+
+	var n *node
+	if p.literal("0") {
+		if logic := p.peek(); logic == "&&" || logic == "||" {
+			// and, or
+			p.pos++
+			b, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if cmp != ">" || p.expect(">") != nil || !p.literal("0") {
+				return nil, p.unexpected()
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+			if !p.literal("1", "0") {
+				return nil, p.unexpected()
+			}
+			if logic == "&&" {
+				return operatorNodeOf(-20, a, b), p.expect(")")
+			}
+			return operatorNodeOf(-21, a, b), p.expect(")")
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		if p.literal("1", "0") {
+			// ifbgt, ifblt against zero
+			n = operatorNodeOf(-18, a, &node{kind: constantNode, value: 0})
+		} else {
+			// ifgtz, ifltz
+			n = operatorNodeOf(-14, a)
+			for i := 0; i < 2; i++ {
+				if i > 0 {
+					if err := p.expect(","); err != nil {
+						return nil, err
+					}
+				}
+				arg, err := p.expr()
+				if err != nil {
+					return nil, err
+				}
+				n.args = append(n.args, arg)
+			}
+		}
+	} else {
+		b, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		if p.literal("1", "0") {
+			// ifbgt, ifblt
+			n = operatorNodeOf(-18, a, b)
+		} else {
+			// ifgt, iflt
+			n = operatorNodeOf(-16, a, b)
+			for i := 0; i < 2; i++ {
+				if i > 0 {
+					if err := p.expect(","); err != nil {
+						return nil, err
+					}
+				}
+				arg, err := p.expr()
+				if err != nil {
+					return nil, err
+				}
+				n.args = append(n.args, arg)
+			}
+		}
+	}
+	if cmp == "<" {
+		n.op-- // the "<" variant always follows the ">" one
+	}
+	return n, p.expect(")")
+}
+
+// compile - translate an expression tree into a program, sharing identical sub-expressions.
+// Returns the program, its constants and the index of the output gene.
+func compile(n *node, numVariables int) (program, constants, int) {
+
+	var code program
+	var consts constants
+	genes := map[string]int{}
+
+	var emit func(n *node) int
+	emit = func(n *node) int {
+		var gene instruction
+		var key string
+		switch n.kind {
+		case variableNode:
+			gene.op = n.op
+			key = fmt.Sprintf("v%d", n.op)
+		case constantNode:
+			key = fmt.Sprintf("c%x", math.Float64bits(n.value))
+			if i, ok := genes[key]; ok {
+				return i
+			}
+			gene.op = numVariables + len(consts)
+			consts = append(consts, n.value)
+		case operatorNode:
+			gene.op = n.op
+			adr := make([]int, 4)
+			for i, arg := range n.args {
+				adr[i] = emit(arg)
+			}
+			gene.adr1, gene.adr2, gene.adr3, gene.adr4 = adr[0], adr[1], adr[2], adr[3]
+			key = fmt.Sprintf("o%d,%v", n.op, adr)
+		}
+		if i, ok := genes[key]; ok {
+			return i
+		}
+		genes[key] = len(code)
+		code = append(code, gene)
+		return len(code) - 1
+	}
+
+	output := emit(n)
+	return code, consts, output
+}
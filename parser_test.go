@@ -0,0 +1,117 @@
+package mep
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseModel(t *testing.T) {
+
+	labels := []string{"x0", "x1", "x2"}
+	x := []float64{2, 3, -4}
+
+	tests := []struct {
+		expr   string
+		result float64
+	}{
+		{"x0*(x1+x2)", -2},
+		{"x0-(x1-x2)", -5},
+		{"x0+x1*x2", -10},
+		{"x0-(-0.500000)", 2.5},
+		{"(1.5e1)/x0", 7.5},
+		{"pow(x0,x1)", 8},
+		{"max(x1,x2)+min(x1,x2)", -1},
+		{"square(inv(x0))", 0.25},
+		{"iif(x2>0,x0,x1)", 3},
+		{"iif(x2<0,x0,x1)", 2},
+		{"iif(x0>x1,x0,x2)", -4},
+		{"iif(x0<x1,x0,x2)", 2},
+		{"iif(x0>x1,1,0)", 0},
+		{"iif(x0<x1,1,0)", 1},
+		{"iif(x0>0 &&x2>0,1,0)", 0},
+		{"iif(x0>0 ||x2>0,1,0)", 1},
+		{"ifgt(x0,x1,x0,x2)", -4},
+	}
+
+	for _, test := range tests {
+		md, err := ParseModel(test.expr, labels)
+		ok(t, err)
+		equals(t, test.result, md.Eval(x))
+	}
+}
+
+func TestParseModelErrors(t *testing.T) {
+
+	labels := []string{"x0", "x1"}
+	for _, expr := range []string{"", "x0+", "x3", "foo(x0)", "max(x0)", "(x0", "x0)", "iif(x0,x1,x0)", "x0 $ x1"} {
+		_, err := ParseModel(expr, labels)
+		if err == nil {
+			t.Errorf("expected error for %q", expr)
+		}
+	}
+}
+
+func TestParseRoundTrip(t *testing.T) {
+
+	m := New(NewPythagorean(20), TotalErrorFF)
+	for _, op := range m.Oper(true) {
+		m.SetOper(op, true)
+	}
+	m.SetConst([]float64{math.Pi}, 2, -1, 1)
+
+	for _, c := range m.pop[0] {
+		for i := 0; i < m.codeLength; i++ {
+			expr := m.parse("", c, i)
+			md, err := m.ParseExpr(expr)
+			ok(t, err)
+			equals(t, expr, md.String())
+			// the text evaluates exactly like the program
+			original := m.model(c)
+			original.output = i
+			expected := original.Predict(m.td.Train)
+			for k, v := range md.Predict(m.td.Train) {
+				if v != expected[k] && !(math.IsNaN(v) && math.IsNaN(expected[k])) {
+					t.Errorf("%s: row %d: expected %v, got %v", expr, k, expected[k], v)
+				}
+			}
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+
+	labels := []string{"a", "b", "c"}
+	a := &node{kind: variableNode, op: 0}
+	b := &node{kind: variableNode, op: 1}
+	c := &node{kind: variableNode, op: 2}
+	num := func(v float64) *node { return &node{kind: constantNode, value: v} }
+
+	tests := []struct {
+		n    *node
+		expr string
+	}{
+		{operatorNodeOf(-4, a, operatorNodeOf(-3, b, c)), "a/(b*c)"},
+		{operatorNodeOf(-3, operatorNodeOf(-4, a, b), c), "a/b*c"},
+		{operatorNodeOf(-2, a, operatorNodeOf(-4, b, c)), "a-b/c"},
+		{operatorNodeOf(-2, a, operatorNodeOf(-2, b, c)), "a-(b-c)"},
+		{operatorNodeOf(-1, a, operatorNodeOf(-1, b, c)), "a+(b+c)"},
+		{operatorNodeOf(-1, operatorNodeOf(-1, a, b), c), "a+b+c"},
+		{operatorNodeOf(-3, operatorNodeOf(-1, a, b), c), "(a+b)*c"},
+		{operatorNodeOf(-2, a, num(-0.5)), "a-(-0.5)"},
+		{operatorNodeOf(-3, num(1e-7), a), "1e-07*a"},
+		{operatorNodeOf(-1, num(math.Inf(-1)), num(math.NaN())), "(-Inf)+NaN"},
+		{operatorNodeOf(-12, operatorNodeOf(-1, a, b), c), "max(a+b,c)"},
+	}
+	for _, test := range tests {
+		expr := test.n.format("", labels)
+		equals(t, test.expr, expr)
+		n, err := parseTree(expr, labels)
+		ok(t, err)
+		equals(t, expr, n.format("", labels))
+	}
+
+	n := operatorNodeOf(-3, num(-1.0/3), a)
+	equals(t, "(-0.3333333333333333)*a", n.format("", labels))
+	equals(t, "(-0.333333)*a", n.formatNumbers("", labels, "%f"))
+	equals(t, "(-0.333)*a", n.formatNumbers("", labels, "%.3g"))
+}
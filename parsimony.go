@@ -0,0 +1,104 @@
+package mep
+
+import (
+	"math/bits"
+)
+
+// SetSizePenalty - add coefficient times the number of active genes (see EffectiveSize) of a gene
+// to its fitness, so that smaller expressions are chosen as outputs and selected (default 0).
+// The fitness reported by BestFitness and compared to the threshold of Solve is without the penalty.
+func (m *Mep) SetSizePenalty(coefficient float64) {
+	if coefficient < 0 {
+		panic("invalid size penalty")
+	}
+	m.sizePenalty = coefficient
+	// initialize population
+	m.randomPopulation()
+}
+
+// SetSizeTieBreak - between outputs and individuals of equal fitness, prefer the one with fewer
+// active genes (lexicographic parsimony pressure, default false)
+func (m *Mep) SetSizeTieBreak(enabled bool) {
+	m.sizeTieBreak = enabled
+	// initialize population
+	m.randomPopulation()
+}
+
+// SetSizeLimit - the genes whose expression has more than maxSize active genes or is deeper than
+// maxDepth cannot be outputs; an individual without a valid output has an infinite fitness and is
+// counted in InvalidEvals. 0 is no limit (default).
+func (m *Mep) SetSizeLimit(maxSize, maxDepth int) {
+	if maxSize < 0 || maxDepth < 0 {
+		panic("invalid size limit")
+	}
+	m.maxSize = maxSize
+	m.maxDepth = maxDepth
+	// initialize population
+	m.randomPopulation()
+}
+
+// BestSize - number of active genes of the best individual and the depth of its expression
+func (m *Mep) BestSize() (int, int) {
+	best := m.pop[m.bestPop][0]
+	return countActive(best.program, best.bestIndex), depth(best.program, best.bestIndex)
+}
+
+// activeSets - for each gene, the set of genes its expression uses (itself included) as a bitset,
+// built from those of its arguments so that the sizes of all the genes take a single pass
+type activeSets struct {
+	words int
+	bits  []uint64
+}
+
+func newActiveSets(codeLength int) activeSets {
+	words := (codeLength + 63) / 64
+	return activeSets{words, make([]uint64, codeLength*words)}
+}
+
+// add - make the set of gene i, whose arguments already have theirs, returning its size
+func (s activeSets) add(i int, gene instruction) int {
+	set := s.bits[i*s.words : (i+1)*s.words]
+	for k := range set {
+		set[k] = 0
+	}
+	set[i/64] = 1 << uint(i%64)
+	if gene.op < 0 {
+		for _, adr := range gene.args() {
+			for k, w := range s.bits[adr*s.words : (adr+1)*s.words] {
+				set[k] |= w
+			}
+		}
+	}
+	size := 0
+	for _, w := range set {
+		size += bits.OnesCount64(w)
+	}
+	return size
+}
+
+// depth - depth of the expression of gene poz, terminals having depth 1
+func depth(code program, poz int) int {
+	depths := make([]int, poz+1)
+	for i := 0; i <= poz; i++ {
+		depths[i] = geneDepth(code[i], depths)
+	}
+	return depths[poz]
+}
+
+// geneDepth - depth of a gene given those of the previous genes
+func geneDepth(gene instruction, depths []int) int {
+	d := 1
+	if gene.op < 0 {
+		for _, adr := range gene.args() {
+			if depths[adr]+1 > d {
+				d = depths[adr] + 1
+			}
+		}
+	}
+	return d
+}
+
+// fitter - whether chromosome a is better than b: by fitness, then by size (see SetSizeTieBreak)
+func fitter(a, b *chromosome) bool {
+	return better(a.fitness, b.fitness) || a.fitness == b.fitness && a.size < b.size
+}
@@ -0,0 +1,103 @@
+package mep
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParsimony(t *testing.T) {
+
+	// target x0*x0, computed by gene 2 with 3 active genes and gene 3 with 2
+	td := TrainingData{Labels: []string{"x0", "x1"}}
+	for i := 1; i <= 10; i++ {
+		td.Train = append(td.Train, []float64{float64(i), float64(i % 3)})
+		td.Target = append(td.Target, float64(i*i))
+	}
+	m := New(td, TotalErrorFF)
+	m.SetPop(2, 1, 10)
+	c := m.pop[0][0].clone()
+	for i := range c.program {
+		c.program[i] = instruction{op: 1} // x1
+	}
+	c.program[0] = instruction{op: 0}                    // x0
+	c.program[1] = instruction{op: -11, adr1: 0}         // abs(x0)
+	c.program[2] = instruction{op: -3, adr1: 1, adr2: 1} // abs(x0)*abs(x0)
+	c.program[3] = instruction{op: -29, adr1: 0}         // square(x0)
+
+	m.eval(m.results[0], &c)
+	equals(t, 2, c.bestIndex)
+	equals(t, 0.0, c.fitness)
+
+	m.SetSizeTieBreak(true)
+	m.eval(m.results[0], &c)
+	equals(t, 3, c.bestIndex)
+	equals(t, 2, c.size)
+	m.SetSizeTieBreak(false)
+
+	m.SetSizePenalty(0.5)
+	m.eval(m.results[0], &c)
+	equals(t, 3, c.bestIndex)
+	equals(t, 1.0, c.fitness)
+	equals(t, 0.0, c.error)
+	m.SetSizePenalty(0)
+
+	m.SetSizeLimit(1, 0)
+	m.eval(m.results[0], &c)
+	equals(t, 0, c.bestIndex)
+	m.SetSizeLimit(0, 1)
+	m.eval(m.results[0], &c)
+	equals(t, 0, c.bestIndex)
+	m.SetSizeLimit(2, 0)
+	m.eval(m.results[0], &c)
+	equals(t, 3, c.bestIndex)
+
+	// sizes break ties in the sorting
+	m.SetSizeLimit(0, 0)
+	m.SetSizeTieBreak(true)
+	pop := subPopulation{c.clone(), c.clone()}
+	pop[1].program[3].op = -11
+	m.eval(m.results[0], &pop[0])
+	m.eval(m.results[0], &pop[1])
+	equals(t, 3, pop[1].size)
+	equals(t, true, pop.Less(0, 1))
+	equals(t, false, pop.Less(1, 0))
+
+	// the best fitness, compared to the threshold of Solve, is without the penalty
+	m = New(NewPythagorean(20), TotalErrorFF)
+	m.SetSizePenalty(1)
+	m.Solve(5, 0, false)
+	md := m.BestModel()
+	equals(t, TotalErrorFF(md.Predict(m.td.Train), m.td.Target), m.BestFitness())
+}
+
+func TestSizeLimit(t *testing.T) {
+
+	m := New(NewPythagorean(20), TotalErrorFF)
+	m.SetPop(20, 2, 30)
+	m.SetSizeLimit(7, 4)
+	m.Solve(10, 0, false)
+	size, d := m.BestSize()
+	if size > 7 || d > 4 {
+		t.Errorf("best individual of size %d and depth %d", size, d)
+	}
+	for _, subPop := range m.pop {
+		for _, c := range subPop {
+			if c.fitness < math.Inf(1) && (countActive(c.program, c.bestIndex) > 7 || depth(c.program, c.bestIndex) > 4) {
+				t.Errorf("individual beyond the size limit")
+			}
+		}
+	}
+}
+
+func TestActiveSets(t *testing.T) {
+
+	// more than 64 genes, the sets spanning several words
+	m := New(NewPythagorean(20), TotalErrorFF)
+	m.SetPop(10, 1, 150)
+	for _, c := range m.pop[0] {
+		sets := newActiveSets(len(c.program))
+		for i, gene := range c.program {
+			equals(t, countActive(c.program, i), sets.add(i, gene))
+		}
+	}
+}
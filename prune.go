@@ -0,0 +1,89 @@
+package mep
+
+// activeGenes - which genes of the program are used to compute the gene at poz
+func activeGenes(code program, poz int) []bool {
+	active := make([]bool, len(code))
+	active[poz] = true
+	for i := poz; i >= 0; i-- {
+		if active[i] && code[i].op < 0 {
+			for _, adr := range code[i].args() {
+				active[adr] = true
+			}
+		}
+	}
+	return active
+}
+
+// prune - copy the genes used to compute gene poz into a compact program, remapping the
+// addresses and dropping the unused constants; the output is the last gene of the result
+func prune(code program, consts constants, numVariables, poz int) (program, constants) {
+
+	active := activeGenes(code, poz)
+	address := make([]int, len(code))
+	constIndex := map[int]int{}
+	var pruned program
+	var prunedConsts constants
+
+	for i := 0; i <= poz; i++ {
+		if !active[i] {
+			continue
+		}
+		gene := instruction{op: code[i].op}
+		if gene.op < 0 {
+			// addresses past the operator's arity are cleared, they may not fit the compact program
+			adr := []*int{&gene.adr1, &gene.adr2, &gene.adr3, &gene.adr4}
+			for k, a := range code[i].args() {
+				*adr[k] = address[a]
+			}
+		} else if gene.op >= numVariables {
+			index, ok := constIndex[gene.op]
+			if !ok {
+				index = len(prunedConsts)
+				constIndex[gene.op] = index
+				prunedConsts = append(prunedConsts, consts[gene.op-numVariables])
+			}
+			gene.op = numVariables + index
+		}
+		address[i] = len(pruned)
+		pruned = append(pruned, gene)
+	}
+	return pruned, prunedConsts
+}
+
+// Prune - copy of the model without the genes (introns) and constants that do not contribute to the output
+func (md *Model) Prune() *Model {
+	pruned := &Model{Labels: md.Labels}
+	pruned.program, pruned.constants = prune(md.program, md.constants, len(md.Labels), md.output)
+	pruned.output = len(pruned.program) - 1
+	return pruned
+}
+
+// EffectiveSize - number of genes contributing to the output
+func (md *Model) EffectiveSize() int {
+	return countActive(md.program, md.output)
+}
+
+// EffectiveSize - number of genes contributing to the output of the best individual,
+// and the average over the whole population (the rest of the code length is introns)
+func (m *Mep) EffectiveSize() (int, float64) {
+	best := m.pop[m.bestPop][0]
+	total := 0
+	count := 0
+	for _, subPop := range m.pop {
+		for _, c := range subPop {
+			total += countActive(c.program, c.bestIndex)
+			count++
+		}
+	}
+	return countActive(best.program, best.bestIndex), float64(total) / float64(count)
+}
+
+func countActive(code program, poz int) int {
+	count := 0
+	for _, active := range activeGenes(code, poz) {
+		if active {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,47 @@
+package mep
+
+import (
+	"testing"
+)
+
+func TestPrune(t *testing.T) {
+
+	m, models := randomModels(50)
+	for _, md := range models {
+		pruned := md.Prune()
+		equals(t, md.EffectiveSize(), pruned.Size())
+		equals(t, pruned.Size(), pruned.EffectiveSize())
+		equals(t, md.String(), pruned.String())
+		for i, gene := range pruned.program {
+			if gene.op < 0 {
+				for _, adr := range gene.args() {
+					if adr >= i {
+						t.Fatalf("%s: gene %d uses gene %d", pruned, i, adr)
+					}
+				}
+			} else if gene.op >= len(pruned.Labels)+len(pruned.constants) {
+				t.Fatalf("%s: gene %d uses a missing constant", pruned, i)
+			}
+		}
+		expected := md.Predict(m.td.Train)
+		for k, v := range pruned.Predict(m.td.Train) {
+			if !sameValue(expected[k], v) {
+				t.Errorf("%s: row %d: expected %v, got %v", md, k, expected[k], v)
+			}
+		}
+	}
+
+	md, err := ParseModel("x0*x0", []string{"x0", "x1"})
+	ok(t, err)
+	md.program = append(program{{op: 1}, {op: -1, adr1: 0, adr2: 0}}, md.program...)
+	md.program[3].adr1 += 2
+	md.program[3].adr2 += 2
+	md.output = 3
+	equals(t, 2, md.EffectiveSize())
+	equals(t, program{{op: 0}, {op: -3}}, md.Prune().program)
+
+	best, mean := m.EffectiveSize()
+	if best < 1 || best > m.codeLength || mean < 1 || mean > float64(m.codeLength) {
+		t.Errorf("effective size: best %d, mean %f", best, mean)
+	}
+}
@@ -0,0 +1,393 @@
+package mep
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// precedence of rendered expressions, from loosest to tightest binding
+const (
+	precSum     = iota + 1 // a+b, a-b
+	precProduct            // a*b, numbers in scientific notation
+	precPower              // a^b
+	precAtom               // variables, numbers, function calls, fractions, parenthesised expressions
+)
+
+// rendered - text of a sub-expression, its precedence and whether it starts with a minus sign
+type rendered struct {
+	text string
+	prec int
+	neg  bool
+}
+
+// needsParens - whether the operand must be parenthesised where precedence prec is required.
+// Operands starting with a minus sign are also parenthesised unless they come first.
+func (r rendered) needsParens(prec int, first bool) bool {
+	return r.prec < prec || (!first && r.neg)
+}
+
+// binary - infix operation, starting with a minus sign if its left operand does
+func binary(left rendered, text string, prec int) rendered {
+	return rendered{text, prec, left.neg && left.prec >= prec}
+}
+
+func atom(text string) rendered {
+	return rendered{text, precAtom, false}
+}
+
+// splitNumber - mantissa and exponent of the shortest representation of v ("" when not scientific)
+func splitNumber(v float64) (string, string) {
+	s := strconv.FormatFloat(v, 'g', -1, 64)
+	if i := strings.IndexByte(s, 'e'); i >= 0 {
+		exp, _ := strconv.Atoi(s[i+1:])
+		return s[:i], strconv.Itoa(exp)
+	}
+	return s, ""
+}
+
+// splitLabel - name and numeric subscript of labels like x0 or x_1
+func splitLabel(label string) (string, string) {
+	name := strings.TrimRight(label, "0123456789")
+	if name == label || name == "" {
+		return label, ""
+	}
+	return strings.TrimSuffix(name, "_"), label[len(name):]
+}
+
+// BestLatex - return the best expression of the population as LaTeX
+func (m *Mep) BestLatex() string {
+	return m.BestModel().Latex()
+}
+
+// BestMathML - return the best expression of the population as MathML
+func (m *Mep) BestMathML() string {
+	return m.BestModel().MathML()
+}
+
+// Latex - the model's expression as LaTeX (math mode, without delimiters)
+func (md *Model) Latex() string {
+	return latex(md.tree().expand(), md.Labels).text
+}
+
+// MathML - the model's expression as a MathML math element
+func (md *Model) MathML() string {
+	return `<math xmlns="http://www.w3.org/1998/Math/MathML">` + mathML(md.tree().expand(), md.Labels).text + `</math>`
+}
+
+var latexEscaper = strings.NewReplacer(`\`, `\backslash `, "_", `\_`, "%", `\%`, "&", `\&`, "#", `\#`, "$", `\$`, "{", `\{`, "}", `\}`)
+
+func latexLabel(label string) string {
+	name, sub := splitLabel(label)
+	if len(name) > 1 {
+		name = `\mathit{` + latexEscaper.Replace(name) + "}"
+	} else {
+		name = latexEscaper.Replace(name)
+	}
+	if sub != "" {
+		return name + "_{" + sub + "}"
+	}
+	return name
+}
+
+func latexNumber(v float64) rendered {
+	switch {
+	case math.IsNaN(v):
+		return atom(`\mathrm{NaN}`)
+	case math.IsInf(v, 1):
+		return atom(`\infty`)
+	case math.IsInf(v, -1):
+		return rendered{`-\infty`, precAtom, true}
+	}
+	mantissa, exp := splitNumber(v)
+	if exp != "" {
+		return rendered{mantissa + ` \times 10^{` + exp + "}", precProduct, v < 0}
+	}
+	return rendered{mantissa, precAtom, v < 0}
+}
+
+// latex - render the tree
+func latex(n *node, labels []string) rendered {
+
+	switch n.kind {
+	case variableNode:
+		return atom(latexLabel(labels[n.op]))
+	case constantNode:
+		return latexNumber(n.value)
+	}
+
+	r := make([]rendered, len(n.args))
+	a := make([]string, len(n.args))
+	for i, arg := range n.args {
+		r[i] = latex(arg, labels)
+		a[i] = r[i].text
+	}
+	operand := func(i, prec int, first bool) string {
+		if r[i].needsParens(prec, first) {
+			return `\left(` + a[i] + `\right)`
+		}
+		return a[i]
+	}
+	fn := func(name string) rendered {
+		return atom(name + `\left(` + strings.Join(a, ", ") + `\right)`)
+	}
+	cases := func(x, y, cond string) rendered {
+		return atom(`\begin{cases} ` + x + ` & \text{if } ` + cond + ` \\ ` + y + ` & \text{otherwise} \end{cases}`)
+	}
+
+	switch n.op {
+	case -1: // +
+		return binary(r[0], operand(0, precSum, true)+" + "+operand(1, precSum, false), precSum)
+	case -2: // -
+		return binary(r[0], operand(0, precSum, true)+" - "+operand(1, precProduct, false), precSum)
+	case -3: // *
+		return binary(r[0], operand(0, precProduct, true)+` \cdot `+operand(1, precProduct, false), precProduct)
+	case -4: // /
+		return atom(`\frac{` + a[0] + "}{" + a[1] + "}")
+	case -5: // sin
+		return fn(`\sin`)
+	case -6: // cos
+		return fn(`\cos`)
+	case -7: // tan
+		return fn(`\tan`)
+	case -8: // exp
+		return rendered{"e^{" + a[0] + "}", precPower, false}
+	case -9: // log
+		return fn(`\ln`)
+	case -10: // sqrt
+		return atom(`\sqrt{` + a[0] + "}")
+	case -11: // abs
+		return atom(`\left|` + a[0] + `\right|`)
+	case -12: // max
+		return fn(`\max`)
+	case -13: // min
+		return fn(`\min`)
+	case -14: // ifgtz
+		return cases(a[1], a[2], a[0]+" > 0")
+	case -15: // ifltz
+		return cases(a[1], a[2], a[0]+" < 0")
+	case -16: // ifgt
+		return cases(a[2], a[3], a[0]+" > "+a[1])
+	case -17: // iflt
+		return cases(a[2], a[3], a[0]+" < "+a[1])
+	case -18: // ifbgt
+		return cases("1", "0", a[0]+" > "+a[1])
+	case -19: // ifblt
+		return cases("1", "0", a[0]+" < "+a[1])
+	case -20: // and
+		return cases("1", "0", a[0]+` > 0 \land `+a[1]+" > 0")
+	case -21: // or
+		return cases("1", "0", a[0]+` > 0 \lor `+a[1]+" > 0")
+	case -22: // pow
+		return rendered{operand(0, precAtom, false) + "^{" + a[1] + "}", precPower, false}
+	case -23: // pow10
+		return rendered{`10^{\operatorname{trunc}\left(` + a[0] + `\right)}`, precPower, false}
+	case -24: // log10
+		return fn(`\log_{10}`)
+	case -25: // log2
+		return fn(`\log_{2}`)
+	case -26: // floor
+		return atom(`\left\lfloor ` + a[0] + ` \right\rfloor`)
+	case -27: // ceil
+		return atom(`\left\lceil ` + a[0] + ` \right\rceil`)
+	case -28: // inv
+		return atom(`\frac{1}{` + a[0] + "}")
+	case -29: // square
+		return rendered{operand(0, precAtom, false) + "^{2}", precPower, false}
+	case -30: // tanh
+		return fn(`\tanh`)
+	case -31: // sigmoid
+		return fn(`\sigma`)
+	case -32: // atan
+		return fn(`\arctan`)
+	case -33: // atan2
+		return fn(`\operatorname{atan2}`)
+	case -34: // fmod
+		return fn(`\operatorname{fmod}`)
+	case -35: // hypot
+		return atom(`\sqrt{` + operand(0, precAtom, false) + "^{2} + " + operand(1, precAtom, false) + "^{2}}")
+	case -36: // neg
+		return rendered{"-" + operand(0, precProduct, false), precProduct, true}
+	case -37: // cube
+		return rendered{operand(0, precAtom, false) + "^{3}", precPower, false}
+	case -38: // cbrt
+		return atom(`\sqrt[3]{` + a[0] + "}")
+	case -39: // gauss
+		return rendered{"e^{-" + operand(0, precAtom, false) + "^{2}}", precPower, false}
+	case -40: // sign
+		return fn(`\operatorname{sgn}`)
+	case -41: // step
+		return cases("1", "0", a[0]+" > 0")
+	}
+	if s, ok := operatorOf(n.op).Format("latex", a); ok {
+		return atom(s)
+	}
+	return fn(`\operatorname{` + strings.Replace(operatorOf(n.op).Name(), "_", `\_`, -1) + "}")
+}
+
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+func mrow(s ...string) string {
+	return "<mrow>" + strings.Join(s, "") + "</mrow>"
+}
+
+func mo(s string) string {
+	return "<mo>" + s + "</mo>"
+}
+
+func mathMLLabel(label string) string {
+	name, sub := splitLabel(label)
+	mi := "<mi>" + xmlEscaper.Replace(name) + "</mi>"
+	if sub != "" {
+		return "<msub>" + mi + "<mn>" + sub + "</mn></msub>"
+	}
+	return mi
+}
+
+func mathMLNumber(v float64) rendered {
+	switch {
+	case math.IsNaN(v):
+		return atom("<mi>NaN</mi>")
+	case math.IsInf(v, 1):
+		return atom("<mi>&#x221E;</mi>")
+	case math.IsInf(v, -1):
+		return rendered{mrow(mo("-"), "<mi>&#x221E;</mi>"), precAtom, true}
+	}
+	mantissa, exp := splitNumber(math.Abs(v))
+	s := "<mn>" + mantissa + "</mn>"
+	if exp != "" {
+		s = mrow(s, mo("&#x00D7;"), "<msup><mn>10</mn><mn>"+exp+"</mn></msup>")
+	}
+	if v < 0 {
+		s = mrow(mo("-"), s)
+	}
+	if exp != "" {
+		return rendered{s, precProduct, v < 0}
+	}
+	return rendered{s, precAtom, v < 0}
+}
+
+// mathML - render the tree as presentation MathML, as a single element
+func mathML(n *node, labels []string) rendered {
+
+	switch n.kind {
+	case variableNode:
+		return atom(mathMLLabel(labels[n.op]))
+	case constantNode:
+		return mathMLNumber(n.value)
+	}
+
+	r := make([]rendered, len(n.args))
+	a := make([]string, len(n.args))
+	for i, arg := range n.args {
+		r[i] = mathML(arg, labels)
+		a[i] = r[i].text
+	}
+	operand := func(i, prec int, first bool) string {
+		if r[i].needsParens(prec, first) {
+			return mrow(mo("("), a[i], mo(")"))
+		}
+		return a[i]
+	}
+	fn := func(name string) rendered {
+		return atom(mrow(name, mo("&#x2061;"), mrow(mo("("), strings.Join(a, mo(",")), mo(")"))))
+	}
+	cases := func(x, y string, cond ...string) rendered {
+		return atom(mrow(mo("{"), "<mtable>"+
+			"<mtr><mtd>"+x+"</mtd><mtd>"+mrow(append([]string{"<mtext>if&#xA0;</mtext>"}, cond...)...)+"</mtd></mtr>"+
+			"<mtr><mtd>"+y+"</mtd><mtd><mtext>otherwise</mtext></mtd></mtr>"+
+			"</mtable>"))
+	}
+	zero := "<mn>0</mn>"
+	one := "<mn>1</mn>"
+
+	switch n.op {
+	case -1: // +
+		return binary(r[0], mrow(operand(0, precSum, true), mo("+"), operand(1, precSum, false)), precSum)
+	case -2: // -
+		return binary(r[0], mrow(operand(0, precSum, true), mo("-"), operand(1, precProduct, false)), precSum)
+	case -3: // *
+		return binary(r[0], mrow(operand(0, precProduct, true), mo("&#x22C5;"), operand(1, precProduct, false)), precProduct)
+	case -4: // /
+		return atom("<mfrac>" + a[0] + a[1] + "</mfrac>")
+	case -5: // sin
+		return fn("<mi>sin</mi>")
+	case -6: // cos
+		return fn("<mi>cos</mi>")
+	case -7: // tan
+		return fn("<mi>tan</mi>")
+	case -8: // exp
+		return rendered{"<msup><mi>e</mi>" + a[0] + "</msup>", precPower, false}
+	case -9: // log
+		return fn("<mi>ln</mi>")
+	case -10: // sqrt
+		return atom("<msqrt>" + a[0] + "</msqrt>")
+	case -11: // abs
+		return atom(mrow(mo("|"), a[0], mo("|")))
+	case -12: // max
+		return fn("<mi>max</mi>")
+	case -13: // min
+		return fn("<mi>min</mi>")
+	case -14: // ifgtz
+		return cases(a[1], a[2], a[0], mo("&gt;"), zero)
+	case -15: // ifltz
+		return cases(a[1], a[2], a[0], mo("&lt;"), zero)
+	case -16: // ifgt
+		return cases(a[2], a[3], a[0], mo("&gt;"), a[1])
+	case -17: // iflt
+		return cases(a[2], a[3], a[0], mo("&lt;"), a[1])
+	case -18: // ifbgt
+		return cases(one, zero, a[0], mo("&gt;"), a[1])
+	case -19: // ifblt
+		return cases(one, zero, a[0], mo("&lt;"), a[1])
+	case -20: // and
+		return cases(one, zero, a[0], mo("&gt;"), zero, mo("&#x2227;"), a[1], mo("&gt;"), zero)
+	case -21: // or
+		return cases(one, zero, a[0], mo("&gt;"), zero, mo("&#x2228;"), a[1], mo("&gt;"), zero)
+	case -22: // pow
+		return rendered{"<msup>" + operand(0, precAtom, false) + a[1] + "</msup>", precPower, false}
+	case -23: // pow10
+		return rendered{"<msup><mn>10</mn>" + mrow("<mi>trunc</mi>", mo("&#x2061;"), mrow(mo("("), a[0], mo(")"))) + "</msup>", precPower, false}
+	case -24: // log10
+		return fn("<msub><mi>log</mi><mn>10</mn></msub>")
+	case -25: // log2
+		return fn("<msub><mi>log</mi><mn>2</mn></msub>")
+	case -26: // floor
+		return atom(mrow(mo("&#x230A;"), a[0], mo("&#x230B;")))
+	case -27: // ceil
+		return atom(mrow(mo("&#x2308;"), a[0], mo("&#x2309;")))
+	case -28: // inv
+		return atom("<mfrac>" + one + a[0] + "</mfrac>")
+	case -29: // square
+		return rendered{"<msup>" + operand(0, precAtom, false) + "<mn>2</mn></msup>", precPower, false}
+	case -30: // tanh
+		return fn("<mi>tanh</mi>")
+	case -31: // sigmoid
+		return fn("<mi>&#x03C3;</mi>")
+	case -32: // atan
+		return fn("<mi>arctan</mi>")
+	case -33: // atan2
+		return fn("<mi>atan2</mi>")
+	case -34: // fmod
+		return fn("<mi>fmod</mi>")
+	case -35: // hypot
+		return atom("<msqrt><msup>" + operand(0, precAtom, false) + "<mn>2</mn></msup>" + mo("+") +
+			"<msup>" + operand(1, precAtom, false) + "<mn>2</mn></msup></msqrt>")
+	case -36: // neg
+		return rendered{mrow(mo("-"), operand(0, precProduct, false)), precProduct, true}
+	case -37: // cube
+		return rendered{"<msup>" + operand(0, precAtom, false) + "<mn>3</mn></msup>", precPower, false}
+	case -38: // cbrt
+		return atom("<mroot>" + a[0] + "<mn>3</mn></mroot>")
+	case -39: // gauss
+		return rendered{"<msup><mi>e</mi>" + mrow(mo("-"), "<msup>"+operand(0, precAtom, false)+"<mn>2</mn></msup>") + "</msup>", precPower, false}
+	case -40: // sign
+		return fn("<mi>sgn</mi>")
+	case -41: // step
+		return cases(one, zero, a[0], mo("&gt;"), zero)
+	}
+	if s, ok := operatorOf(n.op).Format("mathml", a); ok {
+		return atom(s)
+	}
+	return fn("<mi>" + operatorOf(n.op).Name() + "</mi>")
+}
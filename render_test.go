@@ -0,0 +1,58 @@
+package mep
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLatex(t *testing.T) {
+
+	labels := []string{"x0", "y", "rate"}
+	tests := []struct {
+		expr  string
+		latex string
+	}{
+		{"x0+y*rate", `x_{0} + y \cdot \mathit{rate}`},
+		{"(x0+y)*rate", `\left(x_{0} + y\right) \cdot \mathit{rate}`},
+		{"x0-(y-rate)", `x_{0} - \left(y - \mathit{rate}\right)`},
+		{"x0-y*rate", `x_{0} - y \cdot \mathit{rate}`},
+		{"x0/(y*rate)", `\frac{x_{0}}{y \cdot \mathit{rate}}`},
+		{"x0*(-0.500000)", `x_{0} \cdot \left(-0.5\right)`},
+		{"(-0.500000)*x0", `-0.5 \cdot x_{0}`},
+		{"x0+(1e-07)", `x_{0} + 1 \times 10^{-7}`},
+		{"sqrt(square(x0+y))", `\sqrt{\left(x_{0} + y\right)^{2}}`},
+		{"pow(x0,y+(2.000000))", `x_{0}^{y + 2}`},
+		{"exp(x0)*sin(y)", `e^{x_{0}} \cdot \sin\left(y\right)`},
+		{"iif(x0>y,rate,(1.000000))", `\begin{cases} \mathit{rate} & \text{if } x_{0} > y \\ 1 & \text{otherwise} \end{cases}`},
+		{"max(x0,abs(y))", `\max\left(x_{0}, \left|y\right|\right)`},
+	}
+
+	for _, test := range tests {
+		md, err := ParseModel(test.expr, labels)
+		ok(t, err)
+		equals(t, test.latex, md.Latex())
+	}
+}
+
+func TestMathML(t *testing.T) {
+
+	md, err := ParseModel("x0-(x1+(2.000000))", []string{"x0", "x1"})
+	ok(t, err)
+	equals(t, `<math xmlns="http://www.w3.org/1998/Math/MathML"><mrow><msub><mi>x</mi><mn>0</mn></msub><mo>-</mo>`+
+		`<mrow><mo>(</mo><mrow><msub><mi>x</mi><mn>1</mn></msub><mo>+</mo><mn>2</mn></mrow><mo>)</mo></mrow></mrow></math>`, md.MathML())
+
+	// every operator produces well formed xml
+	_, models := randomModels(50)
+	for _, md := range models {
+		decoder := xml.NewDecoder(strings.NewReader(md.MathML()))
+		for {
+			_, err := decoder.Token()
+			if err == io.EOF {
+				break
+			}
+			ok(t, err)
+		}
+	}
+}
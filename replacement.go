@@ -0,0 +1,105 @@
+package mep
+
+import (
+	"sort"
+)
+
+// ReplacementType - how the offspring of a generation replace the sub-population
+type ReplacementType int
+
+const (
+	// SteadyState - each offspring replaces the worst individual, if it is better (default)
+	SteadyState ReplacementType = iota
+	// Generational - the offspring replace the whole sub-population, except its best individuals (see SetElitism)
+	Generational
+	// MuPlusLambda - the best of the sub-population and its offspring (see SetOffspringSize) survive
+	MuPlusLambda
+	// MuCommaLambda - the best of the offspring (see SetOffspringSize) replace the sub-population
+	MuCommaLambda
+	// ReplaceParent - each offspring replaces its parent, if it is better
+	ReplaceParent
+)
+
+// SetReplacement - how the offspring replace the population (default SteadyState)
+func (m *Mep) SetReplacement(replacement ReplacementType) {
+	if replacement < SteadyState || replacement > ReplaceParent {
+		panic("invalid replacement type")
+	}
+	checkReplacement(replacement, m.elitism, m.lambda, m.subPopSize)
+	m.replacement = replacement
+}
+
+// SetElitism - number of best individuals of each sub-population kept by the Generational replacement (default 1)
+func (m *Mep) SetElitism(elitism int) {
+	if elitism < 0 {
+		panic("invalid elitism")
+	}
+	checkReplacement(m.replacement, elitism, m.lambda, m.subPopSize)
+	m.elitism = elitism
+}
+
+// SetOffspringSize - number of offspring (lambda) of each sub-population per generation with the
+// MuPlusLambda and MuCommaLambda replacements (default 0, the sub-population size)
+func (m *Mep) SetOffspringSize(lambda int) {
+	if lambda < 0 {
+		panic("invalid offspring size")
+	}
+	checkReplacement(m.replacement, m.elitism, lambda, m.subPopSize)
+	m.lambda = lambda
+}
+
+// checkReplacement - the elitism and the offspring size of the replacement must suit the
+// sub-population size, checked before any of them is set
+func checkReplacement(replacement ReplacementType, elitism, lambda, subPopSize int) {
+	if replacement == Generational && elitism >= subPopSize {
+		panic("elitism must be less than the sub-population size")
+	}
+	if replacement == MuCommaLambda && lambda != 0 && lambda < subPopSize {
+		panic("offspring size must be at least the sub-population size")
+	}
+}
+
+// numOffspring - number of offspring of each sub-population per generation
+func (m *Mep) numOffspring() int {
+	if m.pareto {
+		return m.subPopSize
+	}
+	switch m.replacement {
+	case Generational:
+		return m.subPopSize - m.elitism
+	case MuPlusLambda, MuCommaLambda:
+		if m.lambda == 0 {
+			return m.subPopSize
+		}
+		return m.lambda
+	}
+	return m.subPopSize
+}
+
+// replace - the offspring enter sub-population p, that is sorted again
+func (m *Mep) replace(p int, offspring subPopulation) {
+	var next subPopulation
+	if m.pareto {
+		m.pop[p] = m.survivors(append(append(next, m.pop[p]...), offspring...))
+		return
+	}
+	switch m.replacement {
+	case Generational:
+		next = append(append(next, m.pop[p][:m.elitism]...), offspring...)
+	case MuPlusLambda:
+		next = append(append(next, m.pop[p]...), offspring...)
+	case MuCommaLambda:
+		next = offspring
+	default:
+		return
+	}
+	sort.Stable(next)
+	m.pop[p] = next[:m.subPopSize:m.subPopSize]
+}
+
+// clone - copy of the chromosome not sharing its program and constants
+func (c chromosome) clone() chromosome {
+	c.program = append(program(nil), c.program...)
+	c.constants = append(constants(nil), c.constants...)
+	return c
+}
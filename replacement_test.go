@@ -0,0 +1,74 @@
+package mep
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestReplacement(t *testing.T) {
+
+	for _, replacement := range []ReplacementType{SteadyState, Generational, MuPlusLambda, MuCommaLambda, ReplaceParent} {
+		m := New(NewPythagorean(20), TotalErrorFF)
+		m.SetPop(20, 2, 20)
+		m.SetReplacement(replacement)
+		m.SetElitism(2)
+		m.SetOffspringSize(30)
+		best := m.BestFitness()
+		for gen := 0; gen < 10; gen++ {
+			m.Evolve()
+			for _, subPop := range m.pop {
+				equals(t, 20, len(subPop))
+				if !sort.IsSorted(subPop) {
+					t.Errorf("%d: sub-population not sorted", replacement)
+				}
+			}
+			// all but the comma replacement keep the best individual
+			if replacement != MuCommaLambda && better(best, m.BestFitness()) {
+				t.Errorf("%d: best fitness %f after %f", replacement, m.BestFitness(), best)
+			}
+			best = m.BestFitness()
+		}
+	}
+}
+
+func TestReplacementSettings(t *testing.T) {
+
+	panics := func(f func()) (panicked bool) {
+		defer func() { panicked = recover() != nil }()
+		f()
+		return false
+	}
+	m := New(NewPythagorean(20), TotalErrorFF)
+	m.SetPop(20, 1, 20)
+	m.SetReplacement(Generational)
+	if !panics(func() { m.SetElitism(20) }) {
+		t.Errorf("elitism of the whole sub-population")
+	}
+	m.SetElitism(10)
+	if !panics(func() { m.SetPop(10, 1, 20) }) {
+		t.Errorf("sub-population smaller than the elitism")
+	}
+	m.Evolve() // the settings are unchanged
+	equals(t, 20, len(m.pop[0]))
+
+	m = New(NewPythagorean(20), TotalErrorFF)
+	m.SetPop(20, 1, 20)
+	m.SetOffspringSize(10) // valid until the comma replacement is chosen
+	if !panics(func() { m.SetReplacement(MuCommaLambda) }) {
+		t.Errorf("comma replacement with fewer offspring than the sub-population")
+	}
+	m.SetOffspringSize(30)
+	m.SetReplacement(MuCommaLambda)
+	if !panics(func() { m.SetPop(40, 1, 20) }) {
+		t.Errorf("sub-population larger than the offspring")
+	}
+}
+
+func TestSift(t *testing.T) {
+	pop := subPopulation{{fitness: 0}, {fitness: 1}, {fitness: 2}, {fitness: 3}}
+	pop[0].fitness = 2.5
+	equals(t, 2, pop.sift(0))
+	pop[3].fitness = 0.5
+	equals(t, 0, pop.sift(3))
+	equals(t, true, sort.IsSorted(pop))
+}
@@ -0,0 +1,280 @@
+package mep
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Candidates - the sub-population a parent is selected from, sorted by ascending fitness
+// (then by size when ties are broken by size, see SetSizeTieBreak)
+type Candidates interface {
+	// Len - number of candidates
+	Len() int
+	// Fitness - fitness of candidate i (lower is better, NaN is the worst)
+	Fitness(i int) float64
+	// Errors - absolute error of candidate i's output on each training case
+	Errors(i int) []float64
+	// Size - number of active genes of candidate i's output
+	Size(i int) int
+}
+
+// Selection - strategy choosing the parents of the offspring
+type Selection interface {
+	// Select - index of the chosen candidate
+	Select(c Candidates) int
+}
+
+// TournamentSelection - the best of Size candidates drawn at random (default, with Size 2);
+// of candidates with equal fitness the first one wins
+type TournamentSelection struct {
+	Size int
+}
+
+// Select - tournament winner
+func (s TournamentSelection) Select(c Candidates) int {
+	p := rand.Intn(c.Len())
+	for i := 1; i < s.Size; i++ {
+		r := rand.Intn(c.Len())
+		if better(c.Fitness(r), c.Fitness(p)) || c.Fitness(r) == c.Fitness(p) && r < p {
+			p = r
+		}
+	}
+	return p
+}
+
+// DoubleTournamentSelection - parsimony pressure by a tournament on size between the winners of two
+// fitness tournaments of Size candidates; the smaller one wins with probability Pressure/2
+// (valid range 1.0 - 2.0, where 1.0 ignores the size)
+type DoubleTournamentSelection struct {
+	Size     int
+	Pressure float64
+}
+
+// Select - size tournament winner
+func (s DoubleTournamentSelection) Select(c Candidates) int {
+	fitness := TournamentSelection{Size: s.Size}
+	a, b := fitness.Select(c), fitness.Select(c)
+	if c.Size(b) < c.Size(a) {
+		a, b = b, a
+	}
+	if rand.Float64() < s.Pressure/2 {
+		return a
+	}
+	return b
+}
+
+// RouletteSelection - fitness proportionate selection, with probabilities proportional to 1/(1+fitness)
+type RouletteSelection struct{}
+
+// Select - spin the roulette wheel
+func (s RouletteSelection) Select(c Candidates) int {
+	weights := make([]float64, c.Len())
+	for i := range weights {
+		if f := c.Fitness(i); f >= 0 && !math.IsInf(f, 1) {
+			weights[i] = 1 / (1 + f)
+		}
+	}
+	return spin(weights)
+}
+
+// RankSelection - linear ranking; Pressure (valid range 1.0 - 2.0) is the expected number
+// of times the best candidate is selected in Len selections, the worst being selected 2-Pressure times
+type RankSelection struct {
+	Pressure float64
+}
+
+// Select - choose by rank
+func (s RankSelection) Select(c Candidates) int {
+	n := c.Len()
+	if n == 1 {
+		return 0
+	}
+	weights := make([]float64, n)
+	for i := range weights {
+		weights[i] = s.Pressure - (2*s.Pressure-2)*float64(i)/float64(n-1)
+	}
+	return spin(weights)
+}
+
+// TruncationSelection - uniformly one of the best Fraction (valid range 0.0 - 1.0) of the candidates
+type TruncationSelection struct {
+	Fraction float64
+}
+
+// Select - one of the best
+func (s TruncationSelection) Select(c Candidates) int {
+	n := int(math.Ceil(s.Fraction * float64(c.Len())))
+	if n < 1 {
+		n = 1
+	}
+	return rand.Intn(n)
+}
+
+// LexicaseSelection - epsilon-lexicase selection: the training cases are taken in random order,
+// each one keeping the candidates whose error is within Epsilon of the best error of the remaining
+// ones, until a single candidate is left. With Epsilon 0 the median absolute deviation of the
+// remaining errors on each case is used.
+type LexicaseSelection struct {
+	Epsilon float64
+}
+
+// Select - filter the candidates case by case
+func (s LexicaseSelection) Select(c Candidates) int {
+	pool := make([]int, c.Len())
+	for i := range pool {
+		pool[i] = i
+	}
+	numCases := len(c.Errors(0))
+	errors := make([]float64, 0, len(pool))
+	for _, k := range rand.Perm(numCases) {
+		if len(pool) == 1 {
+			break
+		}
+		errors = errors[:0]
+		for _, i := range pool {
+			errors = append(errors, caseError(c, i, k))
+		}
+		epsilon := s.Epsilon
+		if epsilon == 0 {
+			epsilon = medianAbsoluteDeviation(errors)
+		}
+		best := math.Inf(1)
+		for _, e := range errors {
+			best = math.Min(best, e)
+		}
+		kept := pool[:0]
+		for j, i := range pool {
+			if errors[j] <= best+epsilon {
+				kept = append(kept, i)
+			}
+		}
+		pool = kept
+	}
+	return pool[rand.Intn(len(pool))]
+}
+
+// caseError - error of candidate i on case k, NaN counting as an infinite error
+func caseError(c Candidates, i, k int) float64 {
+	e := c.Errors(i)[k]
+	if math.IsNaN(e) {
+		return math.Inf(1)
+	}
+	return e
+}
+
+// medianAbsoluteDeviation - median of the distances to the median
+func medianAbsoluteDeviation(values []float64) float64 {
+	m := median(values)
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - m)
+	}
+	mad := median(deviations)
+	if math.IsNaN(mad) || math.IsInf(mad, 0) {
+		return 0
+	}
+	return mad
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// spin - index chosen with probability proportional to its weight, uniformly when all weights are 0
+func spin(weights []float64) int {
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	if !(total > 0) {
+		return rand.Intn(len(weights))
+	}
+	r := rand.Float64() * total
+	for i, w := range weights {
+		r -= w
+		if r < 0 {
+			return i
+		}
+	}
+	return len(weights) - 1 // rounding
+}
+
+// candidates - a sub-population seen by the selection
+type candidates struct {
+	m      *Mep
+	subPop int
+}
+
+func (c candidates) Len() int {
+	return len(c.m.pop[c.subPop])
+}
+
+func (c candidates) Fitness(i int) float64 {
+	return c.m.pop[c.subPop][i].fitness
+}
+
+func (c candidates) Size(i int) int {
+	chromosome := c.m.pop[c.subPop][i]
+	return countActive(chromosome.program, chromosome.bestIndex)
+}
+
+// Errors - computed on first use, and kept until the chromosome is evaluated again
+func (c candidates) Errors(i int) []float64 {
+	chromosome := &c.m.pop[c.subPop][i]
+	if chromosome.errors == nil {
+		signal := c.m.model(*chromosome).Predict(c.m.td.Train)
+		errors := make([]float64, len(signal))
+		for k, v := range signal {
+			errors[k] = math.Abs(v - c.m.td.Target[k])
+		}
+		chromosome.errors = errors
+	}
+	return chromosome.errors
+}
+
+// selectParent - index of a parent in sub-population p
+func (m *Mep) selectParent(p int) int {
+	if m.pareto {
+		return crowdedTournament(m.pop[p])
+	}
+	return m.selection.Select(candidates{m, p})
+}
+
+// SetSelection - strategy choosing the parents (default TournamentSelection{Size: 2})
+func (m *Mep) SetSelection(selection Selection) {
+	switch s := selection.(type) {
+	case nil:
+		panic("invalid selection")
+	case TournamentSelection:
+		if s.Size < 1 {
+			panic("invalid tournament size")
+		}
+	case DoubleTournamentSelection:
+		if s.Size < 1 {
+			panic("invalid tournament size")
+		}
+		if s.Pressure < 1 || s.Pressure > 2 {
+			panic("invalid double tournament pressure")
+		}
+	case RankSelection:
+		if s.Pressure < 1 || s.Pressure > 2 {
+			panic("invalid rank selection pressure")
+		}
+	case TruncationSelection:
+		if s.Fraction <= 0 || s.Fraction > 1 {
+			panic("invalid truncation fraction")
+		}
+	case LexicaseSelection:
+		if s.Epsilon < 0 {
+			panic("invalid lexicase epsilon")
+		}
+	}
+	m.selection = selection
+}
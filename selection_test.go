@@ -0,0 +1,111 @@
+package mep
+
+import (
+	"math"
+	"testing"
+)
+
+// testCandidates - candidates with given fitness, case errors and sizes
+type testCandidates struct {
+	fitness []float64
+	errors  [][]float64
+	sizes   []int
+}
+
+func (c testCandidates) Len() int               { return len(c.fitness) }
+func (c testCandidates) Fitness(i int) float64  { return c.fitness[i] }
+func (c testCandidates) Errors(i int) []float64 { return c.errors[i] }
+func (c testCandidates) Size(i int) int         { return c.sizes[i] }
+
+func countSelections(s Selection, c Candidates, n int) []int {
+	counts := make([]int, c.Len())
+	for i := 0; i < n; i++ {
+		counts[s.Select(c)]++
+	}
+	return counts
+}
+
+func TestSelection(t *testing.T) {
+
+	c := testCandidates{fitness: []float64{0, 1, 3, math.Inf(1), math.NaN()}}
+
+	counts := countSelections(TournamentSelection{Size: 1}, c, 10000)
+	for _, n := range counts {
+		if n < 1700 || n > 2300 {
+			t.Errorf("tournament of 1 not uniform: %v", counts)
+		}
+	}
+	counts = countSelections(TournamentSelection{Size: 4}, c, 10000)
+	if counts[0] < counts[1] || counts[1] < counts[2] || counts[3] < counts[4] {
+		t.Errorf("tournament of 4: %v", counts)
+	}
+
+	// weights 1, 1/2, 1/4, 0, 0
+	counts = countSelections(RouletteSelection{}, c, 10000)
+	if counts[0] < 5300 || counts[0] > 6100 || counts[3] != 0 || counts[4] != 0 {
+		t.Errorf("roulette: %v", counts)
+	}
+
+	counts = countSelections(RankSelection{Pressure: 2}, c, 10000)
+	if counts[0] < counts[1] || counts[1] < counts[2] || counts[4] != 0 {
+		t.Errorf("rank: %v", counts)
+	}
+
+	counts = countSelections(TruncationSelection{Fraction: 0.4}, c, 1000)
+	equals(t, 1000, counts[0]+counts[1])
+}
+
+func TestDoubleTournamentSelection(t *testing.T) {
+
+	// the smaller candidate wins the size tournament whenever it wins a fitness tournament
+	c := testCandidates{fitness: []float64{1, 1}, sizes: []int{5, 2}}
+	counts := countSelections(DoubleTournamentSelection{Size: 1, Pressure: 2}, c, 10000)
+	if counts[1] < 7200 || counts[1] > 7800 {
+		t.Errorf("double tournament: %v", counts)
+	}
+	counts = countSelections(DoubleTournamentSelection{Size: 1, Pressure: 1}, c, 10000)
+	if counts[1] < 4700 || counts[1] > 5300 {
+		t.Errorf("double tournament without pressure: %v", counts)
+	}
+
+	// the fitness comes first
+	c = testCandidates{fitness: []float64{0, 1}, sizes: []int{5, 2}}
+	counts = countSelections(DoubleTournamentSelection{Size: 10, Pressure: 2}, c, 1000)
+	if counts[0] < 990 {
+		t.Errorf("double tournament: %v", counts)
+	}
+}
+
+func TestLexicaseSelection(t *testing.T) {
+
+	// candidate 0 is the best on average, candidates 1 and 2 are specialists
+	c := testCandidates{
+		fitness: []float64{2, 3, 3, 9},
+		errors:  [][]float64{{1, 1}, {0, 3}, {3, 0}, {3, 3}},
+	}
+	counts := countSelections(LexicaseSelection{Epsilon: 0.5}, c, 1000)
+	equals(t, 0, counts[0]+counts[3])
+	if counts[1] < 400 || counts[2] < 400 {
+		t.Errorf("lexicase: %v", counts)
+	}
+
+	// with a large enough epsilon, the generalist survives
+	counts = countSelections(LexicaseSelection{Epsilon: 1}, c, 1000)
+	equals(t, 0, counts[3])
+	if counts[0] == 0 {
+		t.Errorf("lexicase: %v", counts)
+	}
+
+	m := New(NewPythagorean(20), TotalErrorFF)
+	m.SetPop(20, 2, 10)
+	m.SetSelection(LexicaseSelection{})
+	m.Solve(10, 0, false)
+	cand := candidates{m, 0}
+	errors := cand.Errors(0)
+	equals(t, 20, len(errors))
+	total := 0.0
+	for _, e := range errors {
+		total += e
+	}
+	equals(t, math.Round(cand.Fitness(0)*1e6), math.Round(total*1e6))
+}
@@ -0,0 +1,390 @@
+package mep
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// BestExprSimplified - return the best expression of the population, algebraically simplified
+func (m *Mep) BestExprSimplified() string {
+	c := m.pop[m.bestPop][0]
+	return m.simplify(m.tree(c, c.bestIndex)).formatNumbers("", m.td.Labels, m.numberFormat)
+}
+
+// simplify - simplify the tree, keeping the original if the simplified version
+// does not evaluate identically on the training data
+func (m *Mep) simplify(n *node) *node {
+	s := n.simplify()
+	if !m.equivalent(n, s) {
+		return n
+	}
+	return s
+}
+
+// equivalent - check that two trees evaluate to the same values (within rounding) on the training data
+func (m *Mep) equivalent(a, b *node) bool {
+	labels := m.td.Labels[:m.numVariables]
+	ra := newModel(a, labels).Predict(m.td.Train)
+	rb := newModel(b, labels).Predict(m.td.Train)
+	for k := range ra {
+		if !sameValue(ra[k], rb[k]) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameValue(a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	if math.IsInf(a, 0) || math.IsInf(b, 0) {
+		return a == b
+	}
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
+
+func newModel(n *node, labels []string) *Model {
+	md := &Model{Labels: labels}
+	md.program, md.constants, md.output = compile(n, len(labels))
+	return md
+}
+
+func constantNodeOf(value float64) *node {
+	return &node{kind: constantNode, value: value}
+}
+
+// key - canonical string identifying structurally equal trees
+func (n *node) key() string {
+	switch n.kind {
+	case variableNode:
+		return fmt.Sprintf("x%d", n.op)
+	case constantNode:
+		return fmt.Sprintf("c%x", math.Float64bits(n.value))
+	}
+	s := fmt.Sprintf("o%d(", n.op)
+	for i, a := range n.args {
+		if i > 0 {
+			s += ","
+		}
+		s += a.key()
+	}
+	return s + ")"
+}
+
+func (n *node) equal(o *node) bool {
+	return n.key() == o.key()
+}
+
+func (n *node) isConst(value float64) bool {
+	return n.kind == constantNode && n.value == value
+}
+
+// nodeLess - canonical ordering: variables by index, then constants, then operators
+func nodeLess(a, b *node) bool {
+	if a.kind != b.kind {
+		return a.kind < b.kind
+	}
+	switch a.kind {
+	case variableNode:
+		return a.op < b.op
+	case constantNode:
+		return a.value < b.value
+	}
+	return a.key() < b.key()
+}
+
+// simplify - constant folding, identity removal, like-term collection and canonical ordering,
+// repeated until nothing changes
+func (n *node) simplify() *node {
+	key := n.key()
+	for i := 0; i < 10; i++ {
+		n = n.simplifyOnce()
+		k := n.key()
+		if k == key {
+			break
+		}
+		key = k
+	}
+	return n
+}
+
+func (n *node) simplifyOnce() *node {
+
+	if n.kind != operatorNode {
+		return n
+	}
+
+	args := make([]*node, len(n.args))
+	allConst := true
+	for i, a := range n.args {
+		args[i] = a.simplifyOnce()
+		if args[i].kind != constantNode {
+			allConst = false
+		}
+	}
+	n = operatorNodeOf(n.op, args...)
+
+	if allConst {
+		if v := fold(n); !math.IsNaN(v) && !math.IsInf(v, 0) {
+			return constantNodeOf(v)
+		}
+		return n
+	}
+
+	a := args
+	switch n.op {
+	case -1, -2: // +, -
+		return simplifySum(n)
+	case -3, -4, -28, -29: // *, /, inv, square
+		return simplifyProduct(n)
+	case -11: // abs
+		if a[0].isOp(-11, -29) {
+			return a[0]
+		}
+	case -12, -13: // max, min
+		if a[0].equal(a[1]) {
+			return a[0]
+		}
+		if nodeLess(a[1], a[0]) {
+			a[0], a[1] = a[1], a[0]
+		}
+	case -14, -15: // ifgtz, ifltz
+		if a[1].equal(a[2]) {
+			return a[1]
+		}
+		if a[0].kind == constantNode {
+			if (n.op == -14 && a[0].value > 0) || (n.op == -15 && a[0].value < 0) {
+				return a[1]
+			}
+			return a[2]
+		}
+	case -16, -17: // ifgt, iflt
+		if a[2].equal(a[3]) || a[0].equal(a[1]) {
+			return a[3]
+		}
+		if a[0].kind == constantNode && a[1].kind == constantNode {
+			if (n.op == -16 && a[0].value > a[1].value) || (n.op == -17 && a[0].value < a[1].value) {
+				return a[2]
+			}
+			return a[3]
+		}
+	case -18, -19: // ifbgt, ifblt
+		if a[0].equal(a[1]) {
+			return constantNodeOf(0)
+		}
+	case -20: // and
+		if (a[0].kind == constantNode && a[0].value <= 0) || (a[1].kind == constantNode && a[1].value <= 0) {
+			return constantNodeOf(0)
+		}
+		if nodeLess(a[1], a[0]) {
+			a[0], a[1] = a[1], a[0]
+		}
+	case -21: // or
+		if (a[0].kind == constantNode && a[0].value > 0) || (a[1].kind == constantNode && a[1].value > 0) {
+			return constantNodeOf(1)
+		}
+		if nodeLess(a[1], a[0]) {
+			a[0], a[1] = a[1], a[0]
+		}
+	case -22: // pow
+		if a[1].isConst(0) {
+			return constantNodeOf(1)
+		}
+		if a[1].isConst(1) {
+			return a[0]
+		}
+		if a[1].isConst(2) {
+			return operatorNodeOf(-29, a[0])
+		}
+	}
+	return n
+}
+
+// fold - evaluate an operator whose arguments are all constants
+func fold(n *node) float64 {
+	results := make([][]float64, 4)
+	for i := range results {
+		results[i] = []float64{0}
+	}
+	for i, a := range n.args {
+		results[i][0] = a.value
+	}
+	out := []float64{0}
+	execute(instruction{n.op, 0, 1, 2, 3}, results, out)
+	return out[0]
+}
+
+type term struct {
+	coef float64
+	n    *node
+}
+
+// collectSum - flatten a chain of + and - into coefficient*term pairs and a constant
+func collectSum(n *node, sign float64, terms []term, constant *float64) []term {
+	switch {
+	case n.isOp(-1):
+		terms = collectSum(n.args[0], sign, terms, constant)
+		terms = collectSum(n.args[1], sign, terms, constant)
+	case n.isOp(-2):
+		terms = collectSum(n.args[0], sign, terms, constant)
+		terms = collectSum(n.args[1], -sign, terms, constant)
+	case n.kind == constantNode:
+		*constant += sign * n.value
+	case n.isOp(-3) && n.args[0].kind == constantNode:
+		terms = append(terms, term{sign * n.args[0].value, n.args[1]})
+	default:
+		terms = append(terms, term{sign, n})
+	}
+	return terms
+}
+
+// mergeTerms - add up the coefficients of equal terms, drop those that cancel and sort the rest
+func mergeTerms(terms []term) []term {
+	index := map[string]int{}
+	var merged []term
+	for _, t := range terms {
+		k := t.n.key()
+		if i, ok := index[k]; ok {
+			merged[i].coef += t.coef
+		} else {
+			index[k] = len(merged)
+			merged = append(merged, t)
+		}
+	}
+	var result []term
+	for _, t := range merged {
+		if t.coef != 0 {
+			result = append(result, t)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return nodeLess(result[i].n, result[j].n)
+	})
+	return result
+}
+
+func simplifySum(n *node) *node {
+
+	constant := 0.0
+	terms := mergeTerms(collectSum(n, 1, nil, &constant))
+	if math.IsNaN(constant) || math.IsInf(constant, 0) {
+		return n
+	}
+
+	// positive terms first, so that the expression does not start with a negation
+	sort.SliceStable(terms, func(i, j int) bool {
+		return terms[i].coef > 0 && terms[j].coef < 0
+	})
+
+	var result *node
+	for _, t := range terms {
+		x := t.n
+		if math.Abs(t.coef) != 1 {
+			x = operatorNodeOf(-3, constantNodeOf(math.Abs(t.coef)), t.n)
+		}
+		switch {
+		case result == nil && t.coef > 0:
+			result = x
+		case result == nil:
+			result = operatorNodeOf(-3, constantNodeOf(t.coef), t.n)
+		case t.coef > 0:
+			result = operatorNodeOf(-1, result, x)
+		default:
+			result = operatorNodeOf(-2, result, x)
+		}
+	}
+
+	switch {
+	case result == nil:
+		return constantNodeOf(constant)
+	case constant > 0:
+		return operatorNodeOf(-1, result, constantNodeOf(constant))
+	case constant < 0:
+		return operatorNodeOf(-2, result, constantNodeOf(-constant))
+	}
+	return result
+}
+
+// collectProduct - flatten a chain of *, /, inv and square into factor^power pairs and a coefficient
+func collectProduct(n *node, power float64, factors []term, coef *float64) []term {
+	switch {
+	case n.isOp(-3):
+		factors = collectProduct(n.args[0], power, factors, coef)
+		factors = collectProduct(n.args[1], power, factors, coef)
+	case n.isOp(-4):
+		factors = collectProduct(n.args[0], power, factors, coef)
+		factors = collectProduct(n.args[1], -power, factors, coef)
+	case n.isOp(-28):
+		factors = collectProduct(n.args[0], -power, factors, coef)
+	case n.isOp(-29):
+		factors = collectProduct(n.args[0], 2*power, factors, coef)
+	case n.kind == constantNode:
+		*coef *= math.Pow(n.value, power)
+	default:
+		factors = append(factors, term{power, n})
+	}
+	return factors
+}
+
+// power - x^p as x, square(x) or pow(x,p)
+func power(x *node, p float64) *node {
+	switch p {
+	case 1:
+		return x
+	case 2:
+		return operatorNodeOf(-29, x)
+	}
+	return operatorNodeOf(-22, x, constantNodeOf(p))
+}
+
+// product - left associative product of the factors raised to |power|
+func product(factors []term) *node {
+	var result *node
+	for _, f := range factors {
+		x := power(f.n, math.Abs(f.coef))
+		if result == nil {
+			result = x
+		} else {
+			result = operatorNodeOf(-3, result, x)
+		}
+	}
+	return result
+}
+
+func simplifyProduct(n *node) *node {
+
+	coef := 1.0
+	factors := mergeTerms(collectProduct(n, 1, nil, &coef))
+	if math.IsNaN(coef) || math.IsInf(coef, 0) {
+		return n
+	}
+	if coef == 0 {
+		return constantNodeOf(0)
+	}
+
+	var num, den []term
+	for _, f := range factors {
+		if f.coef > 0 {
+			num = append(num, f)
+		} else {
+			den = append(den, f)
+		}
+	}
+
+	result := product(num)
+	if len(den) > 0 {
+		if result == nil {
+			return operatorNodeOf(-4, constantNodeOf(coef), product(den))
+		}
+		result = operatorNodeOf(-4, result, product(den))
+	}
+	switch {
+	case result == nil:
+		return constantNodeOf(coef)
+	case coef != 1:
+		return operatorNodeOf(-3, constantNodeOf(coef), result)
+	}
+	return result
+}
@@ -0,0 +1,48 @@
+package mep
+
+import (
+	"testing"
+)
+
+func TestSimplify(t *testing.T) {
+
+	labels := []string{"x0", "x1"}
+	tests := []struct {
+		expr       string
+		simplified string
+	}{
+		{"x0-x0+x1*(1.000000)/x1", "1"},
+		{"x0+x0", "2*x0"},
+		{"x1+x0", "x0+x1"},
+		{"x1*x0*x0", "square(x0)*x1"},
+		{"x0*(1.000000)+(0.000000)", "x0"},
+		{"(2.000000)*(3.000000)+x0", "x0+6"},
+		{"x1-(x0+x1)", "(-1)*x0"},
+		{"x0/x1/x0", "1/x1"},
+		{"iif(x0>0,x1,x1)", "x1"},
+		{"iif(x0<x0,x0,x1)", "x1"},
+		{"max(x1,x0)", "max(x0,x1)"},
+		{"pow(x0+x1,(1.000000))", "x0+x1"},
+		{"abs(abs(x0))", "abs(x0)"},
+	}
+
+	for _, test := range tests {
+		n, err := parseTree(test.expr, labels)
+		ok(t, err)
+		equals(t, test.simplified, n.simplify().format("", labels))
+	}
+}
+
+func TestSimplifyEquivalence(t *testing.T) {
+
+	m := New(NewPythagorean(20), TotalErrorFF)
+	m.SetConst([]float64{0, 1}, 2, -1, 1)
+	m.SetPop(50, 1, 12)
+
+	for _, c := range m.pop[0] {
+		n := m.tree(c, c.bestIndex)
+		if !m.equivalent(n, n.simplify()) {
+			t.Errorf("%s simplified to %s", n.format("", m.td.Labels), n.simplify().format("", m.td.Labels))
+		}
+	}
+}